@@ -1,8 +1,10 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,6 +20,8 @@ const (
 	defaultEnabled  = true
 	defaultDisabled = false
 	ds8KAPIPort     = "8452"
+	// ds8kTimeLayout is the layout of timestamps in DS8K RESTful API responses.
+	ds8kTimeLayout = "2006-01-02T15:04:05-0700"
 )
 
 var (
@@ -26,18 +30,37 @@ var (
 	requestErrors             *prometheus.Desc
 	authTokenCacheCounterHit  *prometheus.Desc
 	authTokenCacheCounterMiss *prometheus.Desc
-	authTokenCache            sync.Map
-	requestErrorCount         int = 0
-	authTokenMiss             int = 0
-	authTokenHit              int = 0
-	factories                     = make(map[string]func() (Collector, error))
-	collectorState                = make(map[string]*bool)
+	certificateExpiryDesc     *prometheus.Desc
+	clockSkewDesc             *prometheus.Desc
+	collectorSuccessDesc      *prometheus.Desc
+	collectorDurationDesc     *prometheus.Desc
+	scrapeTimeoutDesc         *prometheus.Desc
+	factories                 = make(map[string]func() (Collector, error))
+	collectorState            = make(map[string]*bool)
+
+	// targetCounters holds the request and auth token counters of each target.
+	targetCounters sync.Map
 )
 
+// counters are the counters of one target. Targets are collected concurrently
+// and, in polling mode, from long-lived goroutines, so they are updated
+// atomically.
+type counters struct {
+	requestErrors int64
+	authTokenHit  int64
+	authTokenMiss int64
+}
+
+func countersFor(ipAddress string) *counters {
+	c, _ := targetCounters.LoadOrStore(ipAddress, &counters{})
+	return c.(*counters)
+}
+
 // DS8kCollector implements the prometheus.Collecotor interface
 type DS8kCollector struct {
+	// ctx is the context of the scrape, the collection stops when it is done.
+	ctx        context.Context
 	targets    []utils.Targets
-	location   string
 	Collectors map[string]Collector
 }
 
@@ -47,6 +70,11 @@ func init() {
 	requestErrors = prometheus.NewDesc(prefix+"request_errors_total", "Errors in request to the DS8K Exporter", []string{"target"}, nil)
 	authTokenCacheCounterHit = prometheus.NewDesc(prefix+"authtoken_cache_counter_hit", "Count of authtoken cache hits", []string{"target"}, nil)
 	authTokenCacheCounterMiss = prometheus.NewDesc(prefix+"authtoken_cache_counter_miss", "Count of authtoken cache misses", []string{"target"}, nil)
+	clockSkewDesc = prometheus.NewDesc(prefix+"clock_skew_seconds", "How far the HMC clock is ahead of the exporter clock, with a resolution of one second", []string{"target"}, nil)
+	certificateExpiryDesc = prometheus.NewDesc(prefix+"hmc_certificate_expiry_timestamp_seconds", "Unix timestamp of the expiry of the certificate presented by the HMC", []string{"target"}, nil)
+	collectorSuccessDesc = prometheus.NewDesc(prefix+"scrape_collector_success", "Whether the collector succeeded for one resource", []string{"collector", "target"}, nil)
+	collectorDurationDesc = prometheus.NewDesc(prefix+"scrape_collector_duration_seconds", "Duration of the collector for one resource", []string{"collector", "target"}, nil)
+	scrapeTimeoutDesc = prometheus.NewDesc(prefix+"scrape_timeout", "Whether the scrape timeout was reached before all collectors finished for one resource", []string{"target"}, nil)
 }
 
 func registerCollector(collector string, isDefaultEnabled bool, factory func() (Collector, error)) {
@@ -64,8 +92,9 @@ func registerCollector(collector string, isDefaultEnabled bool, factory func() (
 	factories[collector] = factory
 }
 
-// newDS8kCollector creates a new DS8k Collector.
-func NewDS8kCollector(targets []utils.Targets, location string) (*DS8kCollector, error) {
+// newDS8kCollector creates a new DS8k Collector. The collection of the
+// targets is canceled when ctx is done.
+func NewDS8kCollector(ctx context.Context, targets []utils.Targets) (*DS8kCollector, error) {
 	collectors := make(map[string]Collector)
 	// log.Infof("Enabled collectors:")
 	for key, enabled := range collectorState {
@@ -78,7 +107,7 @@ func NewDS8kCollector(targets []utils.Targets, location string) (*DS8kCollector,
 			collectors[key] = collector
 		}
 	}
-	return &DS8kCollector{targets, location, collectors}, nil
+	return &DS8kCollector{ctx, targets, collectors}, nil
 }
 
 // Describe implements the Prometheus.Collector interface.
@@ -88,6 +117,11 @@ func (c DS8kCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- requestErrors
 	ch <- authTokenCacheCounterHit
 	ch <- authTokenCacheCounterMiss
+	ch <- certificateExpiryDesc
+	ch <- clockSkewDesc
+	ch <- collectorSuccessDesc
+	ch <- collectorDurationDesc
+	ch <- scrapeTimeoutDesc
 
 	for _, col := range c.Collectors {
 		col.Describe(ch)
@@ -101,80 +135,116 @@ func (c DS8kCollector) Collect(ch chan<- prometheus.Metric) {
 	wg := &sync.WaitGroup{}
 	wg.Add(len(hosts))
 	for _, h := range hosts {
-		go c.collectForHost(h, ch, wg)
+		go c.collectForHost(c.ctx, h, ch, wg)
 	}
 	wg.Wait()
 }
 
-func (c *DS8kCollector) collectForHost(host utils.Targets, ch chan<- prometheus.Metric, wg *sync.WaitGroup) {
+func (c *DS8kCollector) collectForHost(ctx context.Context, host utils.Targets, ch chan<- prometheus.Metric, wg *sync.WaitGroup) {
 	defer wg.Done()
+	c.collectTarget(ctx, host, ch)
+}
+
+// collectTarget collects all metrics of one target and returns the names of
+// the collectors that succeeded. When ctx is done, the metrics collected so
+// far are kept and the remaining collectors fail.
+func (c *DS8kCollector) collectTarget(ctx context.Context, host utils.Targets, ch chan<- prometheus.Metric) (succeeded []string) {
 	start := time.Now()
 	success := 0
-	ds8kClient := utils.DS8kClient{
-		UserName:  host.Userid,
-		Password:  host.Password,
-		IpAddress: host.IpAddress,
-		Location:  c.location,
-	}
+	counts := countersFor(host.IpAddress)
 
 	defer func() {
-		ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), ds8kClient.IpAddress)
-		ch <- prometheus.MustNewConstMetric(requestErrors, prometheus.CounterValue, float64(requestErrorCount), ds8kClient.IpAddress)
-		ch <- prometheus.MustNewConstMetric(authTokenCacheCounterMiss, prometheus.CounterValue, float64(authTokenMiss), ds8kClient.IpAddress)
-		ch <- prometheus.MustNewConstMetric(authTokenCacheCounterHit, prometheus.CounterValue, float64(authTokenHit), ds8kClient.IpAddress)
-		ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, float64(success), ds8kClient.IpAddress)
-	}()
-	// Need to get rid of the goto cheat, replacing with a for loop, and ensureing it has backoff and a short circuit
-	lc := 1
-	for lc < 4 {
-		log.Debugf("Looking for cached Auth Token for %s", host.IpAddress)
-		result, ok := authTokenCache.Load(host.IpAddress)
-		if !ok {
-			log.Debug("Authtoken not found in cache.")
-			log.Debugf("Retrieving authToken for %s", host.IpAddress)
-			// get our authtoken for future interactions
-			authtoken, err := ds8kClient.RetriveAuthToken()
-			if err != nil {
-				log.Errorf("Error getting auth token for %s, the error was %v", host.IpAddress, err)
-				requestErrorCount++
-				success = 0
-				return
+		ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), host.IpAddress)
+		ch <- prometheus.MustNewConstMetric(requestErrors, prometheus.CounterValue, float64(atomic.LoadInt64(&counts.requestErrors)), host.IpAddress)
+		ch <- prometheus.MustNewConstMetric(authTokenCacheCounterMiss, prometheus.CounterValue, float64(atomic.LoadInt64(&counts.authTokenMiss)), host.IpAddress)
+		ch <- prometheus.MustNewConstMetric(authTokenCacheCounterHit, prometheus.CounterValue, float64(atomic.LoadInt64(&counts.authTokenHit)), host.IpAddress)
+		ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, float64(success), host.IpAddress)
+		if success == 0 {
+			// No collector ran, report them all as failed so they don't vanish.
+			for name := range c.Collectors {
+				ch <- prometheus.MustNewConstMetric(collectorDurationDesc, prometheus.GaugeValue, 0, name, host.IpAddress)
+				ch <- prometheus.MustNewConstMetric(collectorSuccessDesc, prometheus.GaugeValue, 0, name, host.IpAddress)
 			}
-			authTokenCache.Store(host.IpAddress, authtoken)
-			result, _ := authTokenCache.Load(host.IpAddress)
-			ds8kClient.AuthToken = result.(string)
-			authTokenMiss++
-			success = 1
-		} else {
-			log.Debugf("Authtoken pulled from cache for %s", host.IpAddress)
-			ds8kClient.AuthToken = result.(string)
-			authTokenHit++
-			success = 1
 		}
-		//test to make sure that our auth token is good
-		// if not delete it and loop back
-		validateURL := "https://" + host.IpAddress + ":8452/api/v1/systems"
-		_, err := ds8kClient.CallDS8kAPI(validateURL)
-		if err != nil {
-			authTokenCache.Delete(host.IpAddress)
-			log.Infof("\nInvalidating authToken for %s, re-requesting authtoken....", host.IpAddress)
-			lc++
-		} else {
-			//We have a valid auth token, we can break out of this loop
-			break
+		timeout := 0.0
+		if ctx.Err() == context.DeadlineExceeded {
+			log.Warnf("Scrape timeout reached for %s after %f seconds, returning partial results", host.IpAddress, time.Since(start).Seconds())
+			timeout = 1
+		}
+		ch <- prometheus.MustNewConstMetric(scrapeTimeoutDesc, prometheus.GaugeValue, timeout, host.IpAddress)
+		if expiry, ok := utils.CertificateExpiry(host.IpAddress); ok {
+			ch <- prometheus.MustNewConstMetric(certificateExpiryDesc, prometheus.GaugeValue, float64(expiry.Unix()), host.IpAddress)
 		}
+		if skew, ok := utils.ClockSkew(host.IpAddress); ok {
+			ch <- prometheus.MustNewConstMetric(clockSkewDesc, prometheus.GaugeValue, skew.Seconds(), host.IpAddress)
+		}
+	}()
+	ds8kClient, err := utils.NewDS8kClient(host)
+	if err != nil {
+		log.Errorf("Error creating client for %s, the error was %v", host.IpAddress, err)
+		atomic.AddInt64(&counts.requestErrors, 1)
+		return
 	}
-	if lc >= 4 {
-		// looped and failed multiple times, so need to go further
-		log.Errorf("Error getting auth token for %s, please check network or username and password.", host.IpAddress)
-		requestErrorCount++
-		success = 0
+	// The client keeps the auth token of the target valid across scrapes.
+	cached, err := ds8kClient.Authenticate(ctx)
+	if err != nil {
+		log.Errorf("Error getting auth token for %s, the error was %v", host.IpAddress, err)
+		atomic.AddInt64(&counts.requestErrors, 1)
 		return
 	}
-	for _, col := range c.Collectors {
-		col.Collect(ds8kClient, ch)
+	if cached {
+		log.Debugf("Authtoken pulled from cache for %s", host.IpAddress)
+		atomic.AddInt64(&counts.authTokenHit, 1)
+	} else {
+		atomic.AddInt64(&counts.authTokenMiss, 1)
+	}
+	success = 1
+	for name, col := range c.Collectors {
+		if execute(ctx, name, col, *ds8kClient, ch) {
+			succeeded = append(succeeded, name)
+		}
 	}
+	return succeeded
+}
 
+// execute runs one collector and reports its duration and success. Once ctx
+// is done, the collector is not run anymore and fails.
+func execute(ctx context.Context, name string, col Collector, ds8kClient utils.DS8kClient, ch chan<- prometheus.Metric) bool {
+	begin := time.Now()
+	err := ctx.Err()
+	if err == nil {
+		err = col.Collect(ctx, ds8kClient, ch)
+	}
+	duration := time.Since(begin)
+	var success float64
+	if err != nil {
+		log.Errorf("Collector %s failed for %s after %f seconds: %s", name, ds8kClient.IpAddress, duration.Seconds(), err)
+	} else {
+		log.Debugf("Collector %s succeeded for %s after %f seconds", name, ds8kClient.IpAddress, duration.Seconds())
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(collectorDurationDesc, prometheus.GaugeValue, duration.Seconds(), name, ds8kClient.IpAddress)
+	ch <- prometheus.MustNewConstMetric(collectorSuccessDesc, prometheus.GaugeValue, success, name, ds8kClient.IpAddress)
+	return err == nil
+}
+
+// newStateMetrics returns one gauge per known state, set to 1 for the current
+// state and 0 for all others. An unknown current state is reported as well.
+func newStateMetrics(desc *prometheus.Desc, current string, states []string, labelvalues ...string) []prometheus.Metric {
+	var metrics []prometheus.Metric
+	known := false
+	for _, state := range states {
+		value := 0.0
+		if state == current {
+			value = 1
+			known = true
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labelvalues, state)...))
+	}
+	if !known && current != "" {
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, append(labelvalues, current)...))
+	}
+	return metrics
 }
 
 // Collector is the interface a collector has to implement.
@@ -184,6 +254,5 @@ type Collector interface {
 	Describe(ch chan<- *prometheus.Desc)
 
 	//Collect collects metrics from DS8K RESTful API
-	// Collect(client utils.DS8kClient, ch chan<- prometheus.Metric, labelvalues []string) error
-	Collect(client utils.DS8kClient, ch chan<- prometheus.Metric)
+	Collect(ctx context.Context, client utils.DS8kClient, ch chan<- prometheus.Metric) error
 }
@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixEncryptionGroup    = "ds8k_encryption_group_"
+	prefixKeyServer          = "ds8k_key_server_"
+	encryptionGroupInfoName  = "info"
+	encryptionGroupStateName = "state"
+	keyServerInfoName        = "info"
+	keyServerStateName       = "state"
+	keyServerCertExpiryName  = "certificate_expiry_timestamp_seconds"
+	encryptionGroupInfoDesc  = "Information about the encryption group, value is always 1."
+	encryptionGroupStateDesc = "The state of the encryption group, 1 for the current state and 0 for all others."
+	keyServerInfoDesc        = "Information about the key server, value is always 1."
+	keyServerStateDesc       = "The connectivity of the DS8K to the key server, 1 for the current state and 0 for all others."
+	keyServerCertExpiryDesc  = "Unix timestamp of the expiry of the key manager certificate used with the key server."
+)
+
+var (
+	encryptionGroupInfo   *prometheus.Desc
+	encryptionGroupState  *prometheus.Desc
+	keyServerInfo         *prometheus.Desc
+	keyServerState        *prometheus.Desc
+	keyServerCertExpiry   *prometheus.Desc
+	encryptionGroupStates = []string{"accessible", "inaccessible", "unconfigured", "rekeying"}
+	keyServerStates       = []string{"connected", "disconnected", "degraded"}
+)
+
+func init() {
+	registerCollector("encryption", defaultDisabled, NewEncryptionCollector)
+	encryptionGroupInfo = prometheus.NewDesc(prefixEncryptionGroup+encryptionGroupInfoName, encryptionGroupInfoDesc, []string{"target", "group", "label"}, nil)
+	encryptionGroupState = prometheus.NewDesc(prefixEncryptionGroup+encryptionGroupStateName, encryptionGroupStateDesc, []string{"target", "group", "state"}, nil)
+	keyServerInfo = prometheus.NewDesc(prefixKeyServer+keyServerInfoName, keyServerInfoDesc, []string{"target", "key_server", "address", "port", "type"}, nil)
+	keyServerState = prometheus.NewDesc(prefixKeyServer+keyServerStateName, keyServerStateDesc, []string{"target", "key_server", "state"}, nil)
+	keyServerCertExpiry = prometheus.NewDesc(prefixKeyServer+keyServerCertExpiryName, keyServerCertExpiryDesc, []string{"target", "key_server"}, nil)
+}
+
+// encryptionCollector collects encryption group and key server metrics
+type encryptionCollector struct {
+}
+
+func NewEncryptionCollector() (Collector, error) {
+	return &encryptionCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*encryptionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- encryptionGroupInfo
+	ch <- encryptionGroupState
+	ch <- keyServerInfo
+	ch <- keyServerState
+	ch <- keyServerCertExpiry
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *encryptionCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering encryption collector ...")
+	var lastErr error
+	reqGroupURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/encryption_groups"
+	groupsResp, err := dClient.CallDS8kAPI(ctx, reqGroupURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/encryption_groups' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/encryption_groups': ", groupsResp)
+	// This is a sample output of /api/v1/encryption_groups call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"encryption_groups": [
+	// 			{
+	// 				"id": "1",
+	// 				"label": "ds8k_prod_key",
+	// 				"state": "accessible"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	groupsData := gjson.Get(groupsResp, "data").String()
+	groups := gjson.Get(groupsData, "encryption_groups").Array()
+	for _, group := range groups {
+		labelvalues := []string{dClient.IpAddress, group.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(encryptionGroupInfo, prometheus.GaugeValue, 1, append(labelvalues, group.Get("label").String())...)
+		for _, m := range newStateMetrics(encryptionGroupState, group.Get("state").String(), encryptionGroupStates, labelvalues...) {
+			ch <- m
+		}
+	}
+
+	reqKeyServerURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/key_servers"
+	keyServersResp, err := dClient.CallDS8kAPI(ctx, reqKeyServerURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/key_servers' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/key_servers': ", keyServersResp)
+	// This is a sample output of /api/v1/key_servers call
+	// {
+	// 	"data": {
+	// 		"key_servers": [
+	// 			{
+	// 				"addr": "10.23.1.50",
+	// 				"cert_expiration": "2021-01-31T12:00:00-0500",
+	// 				"id": "1",
+	// 				"port": "3801",
+	// 				"state": "connected",
+	// 				"type": "SKLM"
+	// 			}
+	// 		]
+	// 	}
+	// }
+
+	keyServersData := gjson.Get(keyServersResp, "data").String()
+	keyServers := gjson.Get(keyServersData, "key_servers").Array()
+	for _, keyServer := range keyServers {
+		labelvalues := []string{dClient.IpAddress, keyServer.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(keyServerInfo, prometheus.GaugeValue, 1, append(labelvalues, keyServer.Get("addr").String(), keyServer.Get("port").String(), keyServer.Get("type").String())...)
+		for _, m := range newStateMetrics(keyServerState, keyServer.Get("state").String(), keyServerStates, labelvalues...) {
+			ch <- m
+		}
+		if certExpiration := keyServer.Get("cert_expiration").String(); certExpiration != "" {
+			expiry, err := time.Parse(ds8kTimeLayout, certExpiration)
+			if err != nil {
+				log.Errorln("Parsing key manager certificate expiry failed: ", err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(keyServerCertExpiry, prometheus.GaugeValue, float64(expiry.Unix()), labelvalues...)
+		}
+	}
+	log.Debugln("Leaving encryption collector.")
+	return lastErr
+}
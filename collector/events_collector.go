@@ -0,0 +1,340 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	eventsTotalName  = "ds8k_events_total"
+	problemsOpenName = "ds8k_problems_open"
+	eventsTotalDesc  = "The number of events logged by the DS8K since the exporter started."
+	problemsOpenDesc = "The number of open serviceable problems."
+	// eventForwardTimeout limits how long forwarding new events to the webhook may take.
+	eventForwardTimeout = 10 * time.Second
+)
+
+var (
+	eventsLogFile    = kingpin.Flag("collector.events.log-file", "File to append new events to, one JSON object per line.").String()
+	eventsWebhookURL = kingpin.Flag("collector.events.webhook-url", "URL to POST new events to as a JSON array.").String()
+
+	// eventLogs holds the read positions and counts of the event log per target.
+	eventLogs sync.Map
+
+	eventsTotal  *prometheus.Desc
+	problemsOpen *prometheus.Desc
+)
+
+func init() {
+	registerCollector("events", defaultDisabled, NewEventsCollector)
+	eventsTotal = prometheus.NewDesc(eventsTotalName, eventsTotalDesc, []string{"target", "severity", "type"}, nil)
+	problemsOpen = prometheus.NewDesc(problemsOpenName, problemsOpenDesc, []string{"target", "severity"}, nil)
+}
+
+// eventLog is the event log of one target. The counts and every destination
+// the events are forwarded to have their own read position, so a destination
+// that fails gets the events again without the others getting them twice.
+type eventLog struct {
+	mu      sync.Mutex
+	counted *eventPosition
+	sinks   map[string]*eventPosition
+	counts  map[eventKey]float64
+}
+
+// eventPosition is a read position in the event log. Events are read
+// incrementally after the time of the last seen event, skipping the events
+// already seen at that time. Events without a valid time are recognized by
+// their ID for as long as the DS8K returns them.
+type eventPosition struct {
+	lastTime   time.Time
+	lastIDs    map[string]bool
+	untimedIDs map[string]bool
+}
+
+// eventSink is a destination new events are forwarded to.
+type eventSink struct {
+	name    string
+	forward func(ctx context.Context, events []forwardedEvent) error
+}
+
+type eventKey struct {
+	severity  string
+	eventType string
+}
+
+// forwardedEvent is a new event as it is written to the log file and webhook.
+type forwardedEvent struct {
+	Target   string          `json:"target"`
+	ID       string          `json:"id"`
+	Time     string          `json:"time"`
+	Severity string          `json:"severity"`
+	Type     string          `json:"type"`
+	Event    json.RawMessage `json:"event"`
+}
+
+// eventsCollector collects the event and problem log
+type eventsCollector struct {
+}
+
+func NewEventsCollector() (Collector, error) {
+	return &eventsCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*eventsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- eventsTotal
+	ch <- problemsOpen
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *eventsCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering events collector ...")
+	var lastErr error
+	l, _ := eventLogs.LoadOrStore(dClient.IpAddress, &eventLog{})
+	el := l.(*eventLog)
+	sinks := eventSinks()
+	el.mu.Lock()
+	if el.counted == nil {
+		// Events logged before the exporter started are neither counted nor forwarded.
+		start := dClient.DeviceTime()
+		el.counted = newEventPosition(start)
+		el.sinks = make(map[string]*eventPosition)
+		for _, sink := range sinks {
+			el.sinks[sink.name] = newEventPosition(start)
+		}
+		el.counts = make(map[eventKey]float64)
+	}
+	after := el.counted.lastTime
+	for _, position := range el.sinks {
+		if position.lastTime.Before(after) {
+			after = position.lastTime
+		}
+	}
+	query := url.Values{}
+	query.Set("after", after.Format(ds8kTimeLayout))
+	reqEventURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/events?" + query.Encode()
+	eventsResp, err := dClient.CallDS8kAPI(ctx, reqEventURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/events' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/events': ", eventsResp)
+	// This is a sample output of /api/v1/events?after=afterTime call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"events": [
+	// 			{
+	// 				"description": "Drive module 1.1-D1 failed.",
+	// 				"id": "SE12345",
+	// 				"severity": "error",
+	// 				"time": "2019-05-20T01:44:42-0400",
+	// 				"type": "hardware"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	// Without a response the positions are kept, they would otherwise
+	// forget the events without a valid time.
+	if err == nil {
+		eventsData := gjson.Get(eventsResp, "data").String()
+		events := c.readEvents(dClient, gjson.Get(eventsData, "events").Array())
+		newEvents, counted := el.counted.advance(events)
+		for _, e := range newEvents {
+			if _, err := time.Parse(ds8kTimeLayout, e.Time); err != nil {
+				log.Warnf("Parsing the time of event %s of %s failed: %s", e.ID, dClient.IpAddress, err)
+			}
+			el.counts[eventKey{e.Severity, e.Type}]++
+		}
+		el.counted = counted
+		// The event log stays locked while forwarding, so concurrent scrapes
+		// don't forward the same events twice.
+		for _, sink := range sinks {
+			pending, position := el.sinks[sink.name].advance(events)
+			if len(pending) > 0 {
+				if err := sink.forward(ctx, pending); err != nil {
+					log.Errorf("Forwarding events of %s to the %s failed, retrying with the next scrape: %s", dClient.IpAddress, sink.name, err)
+					lastErr = err
+					continue
+				}
+				log.Debugf("Forwarded %d new events of %s to the %s", len(pending), dClient.IpAddress, sink.name)
+			}
+			el.sinks[sink.name] = position
+		}
+	}
+	for key, count := range el.counts {
+		ch <- prometheus.MustNewConstMetric(eventsTotal, prometheus.CounterValue, count, dClient.IpAddress, key.severity, key.eventType)
+	}
+	el.mu.Unlock()
+
+	if err := c.collectProblems(ctx, dClient, ch); err != nil {
+		lastErr = err
+	}
+	log.Debugln("Leaving events collector.")
+	return lastErr
+}
+
+// readEvents returns the events ordered by their time.
+func (c *eventsCollector) readEvents(dClient utils.DS8kClient, events []gjson.Result) []forwardedEvent {
+	sort.SliceStable(events, func(i, j int) bool {
+		ti, _ := time.Parse(ds8kTimeLayout, events[i].Get("time").String())
+		tj, _ := time.Parse(ds8kTimeLayout, events[j].Get("time").String())
+		return ti.Before(tj)
+	})
+	var read []forwardedEvent
+	for _, event := range events {
+		read = append(read, forwardedEvent{dClient.IpAddress, event.Get("id").String(), event.Get("time").String(), event.Get("severity").String(), event.Get("type").String(), json.RawMessage(event.Raw)})
+	}
+	return read
+}
+
+func newEventPosition(start time.Time) *eventPosition {
+	return &eventPosition{lastTime: start, lastIDs: make(map[string]bool), untimedIDs: make(map[string]bool)}
+}
+
+// advance returns the events after the position and the position after
+// them. The events without a valid time are only remembered while they are
+// part of events.
+func (p *eventPosition) advance(events []forwardedEvent) ([]forwardedEvent, *eventPosition) {
+	next := &eventPosition{lastTime: p.lastTime, lastIDs: make(map[string]bool), untimedIDs: make(map[string]bool)}
+	for id := range p.lastIDs {
+		next.lastIDs[id] = true
+	}
+	var newEvents []forwardedEvent
+	for _, e := range events {
+		eventTime, err := time.Parse(ds8kTimeLayout, e.Time)
+		if err != nil {
+			next.untimedIDs[e.ID] = true
+			if p.untimedIDs[e.ID] {
+				continue
+			}
+		} else {
+			if eventTime.Before(next.lastTime) || (eventTime.Equal(next.lastTime) && next.lastIDs[e.ID]) {
+				continue
+			}
+			if eventTime.After(next.lastTime) {
+				next.lastTime = eventTime
+				next.lastIDs = make(map[string]bool)
+			}
+			next.lastIDs[e.ID] = true
+		}
+		newEvents = append(newEvents, e)
+	}
+	return newEvents, next
+}
+
+// collectProblems counts the open serviceable problems by severity.
+func (c *eventsCollector) collectProblems(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	reqProblemURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/problems"
+	problemsResp, err := dClient.CallDS8kAPI(ctx, reqProblemURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/problems' request failed: ", err)
+		return err
+	}
+	log.Debugln("Response of '/api/v1/problems': ", problemsResp)
+	// This is a sample output of /api/v1/problems call
+	// {
+	// 	"data": {
+	// 		"problems": [
+	// 			{
+	// 				"description": "Drive module 1.1-D1 failed.",
+	// 				"id": "PR1001",
+	// 				"severity": "error",
+	// 				"state": "open",
+	// 				"time": "2019-05-20T01:44:42-0400"
+	// 			}
+	// 		]
+	// 	}
+	// }
+	problemsData := gjson.Get(problemsResp, "data").String()
+	open := map[string]float64{"error": 0, "warning": 0, "info": 0}
+	for _, problem := range gjson.Get(problemsData, "problems").Array() {
+		if problem.Get("state").String() == "open" {
+			open[problem.Get("severity").String()]++
+		}
+	}
+	for severity, count := range open {
+		ch <- prometheus.MustNewConstMetric(problemsOpen, prometheus.GaugeValue, count, dClient.IpAddress, severity)
+	}
+	return nil
+}
+
+// eventSinks returns the configured destinations of new events.
+func eventSinks() []eventSink {
+	var sinks []eventSink
+	if *eventsLogFile != "" {
+		sinks = append(sinks, eventSink{"log file", appendEvents})
+	}
+	if *eventsWebhookURL != "" {
+		sinks = append(sinks, eventSink{"webhook", postEvents})
+	}
+	return sinks
+}
+
+// appendEvents appends the events to the log file.
+func appendEvents(ctx context.Context, events []forwardedEvent) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(*eventsLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// postEvents posts the events to the webhook. The request is canceled when
+// ctx is done.
+func postEvents(ctx context.Context, events []forwardedEvent) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", *eventsWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: eventForwardTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s returned %s", *eventsWebhookURL, resp.Status)
+	}
+	return nil
+}
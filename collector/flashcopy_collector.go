@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixFlashCopy              = "ds8k_flashcopy_"
+	flashCopyStateName           = "state"
+	flashCopyOutOfSyncTracksName = "out_of_sync_tracks"
+	flashCopyPersistentName      = "persistent"
+	flashCopyIncrementalName     = "incremental"
+	flashCopyRelationshipsName   = "relationships"
+	flashCopyStateDesc           = "The state of the FlashCopy relationship, 1 for the current state and 0 for all others."
+	flashCopyOutOfSyncTracksDesc = "The number of tracks not yet copied from the source to the target volume by the background copy."
+	flashCopyPersistentDesc      = "Whether the FlashCopy relationship is persistent (1) or not (0)."
+	flashCopyIncrementalDesc     = "Whether change recording is enabled for incremental FlashCopy (1) or not (0)."
+	flashCopyRelationshipsDesc   = "The number of FlashCopy relationships with a source volume in the LSS."
+)
+
+var (
+	flashCopyState           *prometheus.Desc
+	flashCopyOutOfSyncTracks *prometheus.Desc
+	flashCopyPersistent      *prometheus.Desc
+	flashCopyIncremental     *prometheus.Desc
+	flashCopyRelationships   *prometheus.Desc
+	flashCopyStates          = []string{"valid", "validation_required", "tgt_failed", "volume_inaccessible"}
+)
+
+func init() {
+	registerCollector("flashcopy", defaultDisabled, NewFlashCopyCollector)
+	labelnames := []string{"target", "source_volume", "target_volume"}
+	flashCopyState = prometheus.NewDesc(prefixFlashCopy+flashCopyStateName, flashCopyStateDesc, append(labelnames, "state"), nil)
+	flashCopyOutOfSyncTracks = prometheus.NewDesc(prefixFlashCopy+flashCopyOutOfSyncTracksName, flashCopyOutOfSyncTracksDesc, labelnames, nil)
+	flashCopyPersistent = prometheus.NewDesc(prefixFlashCopy+flashCopyPersistentName, flashCopyPersistentDesc, labelnames, nil)
+	flashCopyIncremental = prometheus.NewDesc(prefixFlashCopy+flashCopyIncrementalName, flashCopyIncrementalDesc, labelnames, nil)
+	flashCopyRelationships = prometheus.NewDesc(prefixFlashCopy+flashCopyRelationshipsName, flashCopyRelationshipsDesc, []string{"target", "lss"}, nil)
+}
+
+// flashCopyCollector collects FlashCopy relationship metrics
+type flashCopyCollector struct {
+}
+
+func NewFlashCopyCollector() (Collector, error) {
+	return &flashCopyCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*flashCopyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- flashCopyState
+	ch <- flashCopyOutOfSyncTracks
+	ch <- flashCopyPersistent
+	ch <- flashCopyIncremental
+	ch <- flashCopyRelationships
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *flashCopyCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering flashcopy collector ...")
+	reqFlashCopyURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/cs/flashcopies"
+	flashCopiesResp, err := dClient.CallDS8kAPI(ctx, reqFlashCopyURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/cs/flashcopies' request failed: ", err)
+		return err
+	}
+	log.Debugln("Response of '/api/v1/cs/flashcopies': ", flashCopiesResp)
+	// This is a sample output of /api/v1/cs/flashcopies call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"flashcopies": [
+	// 			{
+	// 				"backgroundcopy": "enabled",
+	// 				"id": "0002:0102",
+	// 				"link": {
+	// 					"href": "https:/10.23.1.10:8452/api/v1/cs/flashcopies/0002:0102",
+	// 					"rel": "self"
+	// 				},
+	// 				"out_of_sync_tracks": "1024",
+	// 				"persistent": "enabled",
+	// 				"recording": "enabled",
+	// 				"state": "valid",
+	// 				"volume_pairs": [
+	// 					{
+	// 						"source_volume": {
+	// 							"id": "0002"
+	// 						},
+	// 						"target_volume": {
+	// 							"id": "0102"
+	// 						}
+	// 					}
+	// 				]
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	flashCopiesData := gjson.Get(flashCopiesResp, "data").String()
+	flashCopies := gjson.Get(flashCopiesData, "flashcopies").Array()
+	relationshipsPerLSS := make(map[string]int)
+	for _, flashCopy := range flashCopies {
+		for _, pair := range flashCopy.Get("volume_pairs").Array() {
+			sourceVolume := pair.Get("source_volume.id").String()
+			labelvalues := []string{dClient.IpAddress, sourceVolume, pair.Get("target_volume.id").String()}
+			for _, m := range newStateMetrics(flashCopyState, flashCopy.Get("state").String(), flashCopyStates, labelvalues...) {
+				ch <- m
+			}
+			ch <- prometheus.MustNewConstMetric(flashCopyOutOfSyncTracks, prometheus.GaugeValue, flashCopy.Get("out_of_sync_tracks").Float(), labelvalues...)
+			ch <- prometheus.MustNewConstMetric(flashCopyPersistent, prometheus.GaugeValue, enabledValue(flashCopy.Get("persistent").String()), labelvalues...)
+			ch <- prometheus.MustNewConstMetric(flashCopyIncremental, prometheus.GaugeValue, enabledValue(flashCopy.Get("recording").String()), labelvalues...)
+			relationshipsPerLSS[volumeLSS(sourceVolume)]++
+		}
+	}
+	for lss, count := range relationshipsPerLSS {
+		ch <- prometheus.MustNewConstMetric(flashCopyRelationships, prometheus.GaugeValue, float64(count), dClient.IpAddress, lss)
+	}
+	log.Debugln("Leaving flashcopy collector.")
+	return nil
+}
+
+// enabledValue converts an "enabled"/"disabled" flag of the DS8K RESTful API to 1 or 0.
+func enabledValue(flag string) float64 {
+	if flag == "enabled" {
+		return 1
+	}
+	return 0
+}
+
+// volumeLSS returns the logical subsystem of a volume, which is given by the
+// first two hex digits of the volume ID.
+func volumeLSS(volumeID string) string {
+	if len(volumeID) < 2 {
+		return volumeID
+	}
+	return volumeID[:2]
+}
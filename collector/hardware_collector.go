@@ -0,0 +1,340 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixFrame        = "ds8k_frame_"
+	prefixEnclosure    = "ds8k_enclosure_"
+	prefixArray        = "ds8k_array_"
+	prefixRank         = "ds8k_rank_"
+	prefixDrive        = "ds8k_drive_"
+	frameInfoName      = "info"
+	frameStateName     = "state"
+	enclosureInfoName  = "info"
+	enclosureStateName = "state"
+	arrayInfoName      = "info"
+	arrayStateName     = "state"
+	arrayDataStateName = "data_state"
+	rankInfoName       = "info"
+	rankStateName      = "state"
+	rankDataStateName  = "data_state"
+	driveInfoName      = "info"
+	driveStateName     = "state"
+	driveCapacityName  = "capacity_bytes"
+	frameInfoDesc      = "Information about the frame, value is always 1."
+	frameStateDesc     = "The state of the frame, 1 for the current state and 0 for all others."
+	enclosureInfoDesc  = "Information about the storage enclosure, value is always 1."
+	enclosureStateDesc = "The state of the storage enclosure, 1 for the current state and 0 for all others."
+	arrayInfoDesc      = "Information about the array, value is always 1."
+	arrayStateDesc     = "The state of the array, 1 for the current state and 0 for all others."
+	arrayDataStateDesc = "The data state of the array, 1 for the current state and 0 for all others."
+	rankInfoDesc       = "Information about the rank and the pool it is assigned to, value is always 1."
+	rankStateDesc      = "The state of the rank, 1 for the current state and 0 for all others."
+	rankDataStateDesc  = "The data state of the rank, 1 for the current state and 0 for all others."
+	driveInfoDesc      = "Information about the drive module, value is always 1."
+	driveStateDesc     = "The state of the drive module, 1 for the current state and 0 for all others."
+	driveCapacityDesc  = "The capacity of the drive module in bytes."
+)
+
+var (
+	frameInfo      *prometheus.Desc
+	frameState     *prometheus.Desc
+	enclosureInfo  *prometheus.Desc
+	enclosureState *prometheus.Desc
+	arrayInfo      *prometheus.Desc
+	arrayState     *prometheus.Desc
+	arrayDataState *prometheus.Desc
+	rankInfo       *prometheus.Desc
+	rankState      *prometheus.Desc
+	rankDataState  *prometheus.Desc
+	driveInfo      *prometheus.Desc
+	driveState     *prometheus.Desc
+	driveCapacity  *prometheus.Desc
+
+	frameStates     = []string{"online", "offline"}
+	enclosureStates = []string{"online", "offline", "degraded"}
+	arrayStates     = []string{"assigned", "unassigned", "unavailable"}
+	rankStates      = []string{"normal", "configuring", "reserved", "depopulating", "deconfiguring"}
+	// dataStates are the data states of arrays and ranks.
+	dataStates  = []string{"normal", "degraded", "rebuilding", "read_only", "failed", "inaccessible"}
+	driveStates = []string{"normal", "spare", "rebuilding", "failed", "not_installed"}
+)
+
+func init() {
+	registerCollector("hardware", defaultDisabled, NewHardwareCollector)
+	frameInfo = prometheus.NewDesc(prefixFrame+frameInfoName, frameInfoDesc, []string{"target", "frame", "type", "location"}, nil)
+	frameState = prometheus.NewDesc(prefixFrame+frameStateName, frameStateDesc, []string{"target", "frame", "state"}, nil)
+	enclosureInfo = prometheus.NewDesc(prefixEnclosure+enclosureInfoName, enclosureInfoDesc, []string{"target", "enclosure", "frame", "type", "location"}, nil)
+	enclosureState = prometheus.NewDesc(prefixEnclosure+enclosureStateName, enclosureStateDesc, []string{"target", "enclosure", "state"}, nil)
+	arrayInfo = prometheus.NewDesc(prefixArray+arrayInfoName, arrayInfoDesc, []string{"target", "array", "raidtype", "drive_class"}, nil)
+	arrayState = prometheus.NewDesc(prefixArray+arrayStateName, arrayStateDesc, []string{"target", "array", "state"}, nil)
+	arrayDataState = prometheus.NewDesc(prefixArray+arrayDataStateName, arrayDataStateDesc, []string{"target", "array", "state"}, nil)
+	rankInfo = prometheus.NewDesc(prefixRank+rankInfoName, rankInfoDesc, []string{"target", "rank", "array", "raidtype", "stgtype", "pool"}, nil)
+	rankState = prometheus.NewDesc(prefixRank+rankStateName, rankStateDesc, []string{"target", "rank", "state"}, nil)
+	rankDataState = prometheus.NewDesc(prefixRank+rankDataStateName, rankDataStateDesc, []string{"target", "rank", "state"}, nil)
+	driveInfo = prometheus.NewDesc(prefixDrive+driveInfoName, driveInfoDesc, []string{"target", "drive", "enclosure", "array", "class", "interface"}, nil)
+	driveState = prometheus.NewDesc(prefixDrive+driveStateName, driveStateDesc, []string{"target", "drive", "state"}, nil)
+	driveCapacity = prometheus.NewDesc(prefixDrive+driveCapacityName, driveCapacityDesc, []string{"target", "drive"}, nil)
+}
+
+// hardwareCollector collects frame, enclosure, array, rank and drive metrics
+type hardwareCollector struct {
+}
+
+func NewHardwareCollector() (Collector, error) {
+	return &hardwareCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*hardwareCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- frameInfo
+	ch <- frameState
+	ch <- enclosureInfo
+	ch <- enclosureState
+	ch <- arrayInfo
+	ch <- arrayState
+	ch <- arrayDataState
+	ch <- rankInfo
+	ch <- rankState
+	ch <- rankDataState
+	ch <- driveInfo
+	ch <- driveState
+	ch <- driveCapacity
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *hardwareCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering hardware collector ...")
+	var lastErr error
+	frames, err := c.list(ctx, dClient, "frames")
+	if err != nil {
+		lastErr = err
+	}
+	// This is a sample output of /api/v1/frames call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"frames": [
+	// 			{
+	// 				"id": "1",
+	// 				"loc": "U2107.D01.1234567",
+	// 				"state": "online",
+	// 				"type": "base"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	for _, frame := range frames {
+		labelvalues := []string{dClient.IpAddress, frame.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(frameInfo, prometheus.GaugeValue, 1, append(labelvalues, frame.Get("type").String(), frame.Get("loc").String())...)
+		for _, m := range newStateMetrics(frameState, frame.Get("state").String(), frameStates, labelvalues...) {
+			ch <- m
+		}
+	}
+
+	enclosures, err := c.list(ctx, dClient, "enclosures")
+	if err != nil {
+		lastErr = err
+	}
+	// This is a sample output of /api/v1/enclosures call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"enclosures": [
+	// 			{
+	// 				"frame": {
+	// 					"id": "1"
+	// 				},
+	// 				"id": "1.1",
+	// 				"loc": "U2107.D01.1234567-P1",
+	// 				"state": "online",
+	// 				"type": "HPFE Gen2"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	for _, enclosure := range enclosures {
+		labelvalues := []string{dClient.IpAddress, enclosure.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(enclosureInfo, prometheus.GaugeValue, 1, append(labelvalues, enclosure.Get("frame.id").String(), enclosure.Get("type").String(), enclosure.Get("loc").String())...)
+		for _, m := range newStateMetrics(enclosureState, enclosure.Get("state").String(), enclosureStates, labelvalues...) {
+			ch <- m
+		}
+	}
+
+	arrays, err := c.list(ctx, dClient, "arrays")
+	if err != nil {
+		lastErr = err
+	}
+	// This is a sample output of /api/v1/arrays call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"arrays": [
+	// 			{
+	// 				"datastate": "normal",
+	// 				"diskclass": "flash",
+	// 				"id": "A0",
+	// 				"raidtype": "6",
+	// 				"state": "assigned"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	for _, array := range arrays {
+		labelvalues := []string{dClient.IpAddress, array.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(arrayInfo, prometheus.GaugeValue, 1, append(labelvalues, array.Get("raidtype").String(), array.Get("diskclass").String())...)
+		for _, m := range newStateMetrics(arrayState, array.Get("state").String(), arrayStates, labelvalues...) {
+			ch <- m
+		}
+		for _, m := range newStateMetrics(arrayDataState, array.Get("datastate").String(), dataStates, labelvalues...) {
+			ch <- m
+		}
+	}
+
+	// The pools are only listed to label the ranks with the pool name.
+	pools, err := c.list(ctx, dClient, "pools")
+	if err != nil {
+		lastErr = err
+	}
+	poolNames := make(map[string]string)
+	for _, pool := range pools {
+		poolNames[pool.Get("id").String()] = pool.Get("name").String() + "_" + pool.Get("id").String()
+	}
+	ranks, err := c.list(ctx, dClient, "ranks")
+	if err != nil {
+		lastErr = err
+	}
+	// This is a sample output of /api/v1/ranks call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"ranks": [
+	// 			{
+	// 				"array": {
+	// 					"id": "A0"
+	// 				},
+	// 				"datastate": "normal",
+	// 				"id": "R0",
+	// 				"pool": {
+	// 					"id": "P0"
+	// 				},
+	// 				"raidtype": "6",
+	// 				"state": "normal",
+	// 				"stgtype": "fb"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	for _, rank := range ranks {
+		labelvalues := []string{dClient.IpAddress, rank.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(rankInfo, prometheus.GaugeValue, 1, append(labelvalues, rank.Get("array.id").String(), rank.Get("raidtype").String(), rank.Get("stgtype").String(), poolNames[rank.Get("pool.id").String()])...)
+		for _, m := range newStateMetrics(rankState, rank.Get("state").String(), rankStates, labelvalues...) {
+			ch <- m
+		}
+		for _, m := range newStateMetrics(rankDataState, rank.Get("datastate").String(), dataStates, labelvalues...) {
+			ch <- m
+		}
+	}
+
+	drives, err := c.list(ctx, dClient, "drives")
+	if err != nil {
+		lastErr = err
+	}
+	// This is a sample output of /api/v1/drives call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"drives": [
+	// 			{
+	// 				"array": {
+	// 					"id": "A0"
+	// 				},
+	// 				"cap": "1600000000000",
+	// 				"class": "flash",
+	// 				"enclosure": {
+	// 					"id": "1.1"
+	// 				},
+	// 				"id": "1.1-D1",
+	// 				"interface": "SAS",
+	// 				"state": "normal"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	for _, drive := range drives {
+		labelvalues := []string{dClient.IpAddress, drive.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(driveInfo, prometheus.GaugeValue, 1, append(labelvalues, drive.Get("enclosure.id").String(), drive.Get("array.id").String(), drive.Get("class").String(), drive.Get("interface").String())...)
+		for _, m := range newStateMetrics(driveState, drive.Get("state").String(), driveStates, labelvalues...) {
+			ch <- m
+		}
+		ch <- prometheus.MustNewConstMetric(driveCapacity, prometheus.GaugeValue, drive.Get("cap").Float(), labelvalues...)
+	}
+	log.Debugln("Leaving hardware collector.")
+	return lastErr
+}
+
+// list returns the items of the resource, for example "frames" for
+// /api/v1/frames.
+func (c *hardwareCollector) list(ctx context.Context, dClient utils.DS8kClient, resource string) ([]gjson.Result, error) {
+	reqURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/" + resource
+	resp, err := dClient.CallDS8kAPI(ctx, reqURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/"+resource+"' request failed: ", err)
+		return nil, err
+	}
+	log.Debugln("Response of '/api/v1/"+resource+"': ", resp)
+	data := gjson.Get(resp, "data").String()
+	return gjson.Get(data, resource).Array(), nil
+}
@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixHost            = "ds8k_host_"
+	hostInfoName          = "info"
+	hostPortStateName     = "port_state"
+	volumeHostMappingName = "ds8k_volume_host_mapping"
+	hostInfoDesc          = "Information about the host, value is always 1."
+	hostPortStateDesc     = "The state of the host port, 1 for the current state and 0 for all others."
+	volumeHostMappingDesc = "The volume is mapped to the host, value is always 1."
+)
+
+var (
+	hostInfo          *prometheus.Desc
+	hostPortState     *prometheus.Desc
+	volumeHostMapping *prometheus.Desc
+	hostPortStates    = []string{"logged in", "logged out"}
+)
+
+func init() {
+	registerCollector("host", defaultDisabled, NewHostCollector)
+	hostInfo = prometheus.NewDesc(prefixHost+hostInfoName, hostInfoDesc, []string{"target", "host", "type", "addrmode"}, nil)
+	hostPortState = prometheus.NewDesc(prefixHost+hostPortStateName, hostPortStateDesc, []string{"target", "host", "wwpn", "state"}, nil)
+	volumeHostMapping = prometheus.NewDesc(volumeHostMappingName, volumeHostMappingDesc, []string{"target", "volume", "host", "lunid"}, nil)
+}
+
+// hostCollector collects host, host port and volume mapping metrics
+type hostCollector struct {
+}
+
+func NewHostCollector() (Collector, error) {
+	return &hostCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*hostCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hostInfo
+	ch <- hostPortState
+	ch <- volumeHostMapping
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *hostCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering hosts collector ...")
+	reqHostURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/hosts"
+	var lastErr error
+	hostsResp, err := dClient.CallDS8kAPI(ctx, reqHostURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/hosts' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/hosts': ", hostsResp)
+	// This is a sample output of /api/v1/hosts call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 12,
+	// 		"total_counts": 12
+	// 	},
+	// 	"data": {
+	// 		"hosts": [
+	// 			{
+	// 				"addrmode": "SCSI mask",
+	// 				"addrdiscovery": "lunpolling",
+	// 				"hosttype": "VMware",
+	// 				"link": {
+	// 					"href": "https:/10.23.1.10:8452/api/v1/hosts/esx01",
+	// 					"rel": "self"
+	// 				},
+	// 				"name": "esx01",
+	// 				"state": "online",
+	// 				"volumes": {
+	// 					"link": {
+	// 						"href": "https:/10.23.1.10:8452/api/v1/hosts/esx01/volumes",
+	// 						"rel": "self"
+	// 					}
+	// 				}
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	hostsData := gjson.Get(hostsResp, "data").String()
+	hosts := gjson.Get(hostsData, "hosts").Array()
+	for _, host := range hosts {
+		hostName := host.Get("name").String()
+		ch <- prometheus.MustNewConstMetric(hostInfo, prometheus.GaugeValue, 1, dClient.IpAddress, hostName, host.Get("hosttype").String(), host.Get("addrmode").String())
+
+		requestVolume := "/api/v1/hosts/" + url.PathEscape(hostName) + "/volumes"
+		reqVolumeURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + requestVolume
+		volumesResp, err := dClient.CallDS8kAPI(ctx, reqVolumeURL)
+		if err != nil {
+			log.Errorln("Executing '"+requestVolume+"' request failed: ", err)
+			lastErr = err
+			continue
+		}
+		log.Debugln("Response of '"+requestVolume+"': ", volumesResp)
+		// This is the sample output of /api/v1/hosts/hostName/volumes
+		// {
+		// 	"counts": {
+		// 		"data_counts": 2,
+		// 		"total_counts": 2
+		// 	},
+		// 	"data": {
+		// 		"volumes": [
+		// 			{
+		// 				"id": "0002",
+		// 				"link": {
+		// 					"href": "https:/10.23.1.10:8452/api/v1/volumes/0002",
+		// 					"rel": "self"
+		// 				},
+		// 				"lunid": "40004000",
+		// 				"name": "mgr_hm1_code"
+		// 			}
+		// 		]
+		// 	},
+		// 	"server": {
+		// 		"code": "",
+		// 		"message": "Operation done successfully.",
+		// 		"status": "ok"
+		// 	}
+		// }
+
+		volumesData := gjson.Get(volumesResp, "data").String()
+		volumes := gjson.Get(volumesData, "volumes").Array()
+		for _, volume := range volumes {
+			// The volume label matches the one exported by the volume collector.
+			volumeName := volume.Get("name").String() + "_" + volume.Get("id").String()
+			ch <- prometheus.MustNewConstMetric(volumeHostMapping, prometheus.GaugeValue, 1, dClient.IpAddress, volumeName, hostName, volume.Get("lunid").String())
+		}
+	}
+
+	reqHostPortURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/host_ports"
+	hostPortsResp, err := dClient.CallDS8kAPI(ctx, reqHostPortURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/host_ports' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/host_ports': ", hostPortsResp)
+	// This is a sample output of /api/v1/host_ports call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 24,
+	// 		"total_counts": 24
+	// 	},
+	// 	"data": {
+	// 		"host_ports": [
+	// 			{
+	// 				"host": {
+	// 					"link": {
+	// 						"href": "https:/10.23.1.10:8452/api/v1/hosts/esx01",
+	// 						"rel": "self"
+	// 					},
+	// 					"name": "esx01"
+	// 				},
+	// 				"hosttype": "VMware",
+	// 				"link": {
+	// 					"href": "https:/10.23.1.10:8452/api/v1/host_ports/10000000C9A1B2C3",
+	// 					"rel": "self"
+	// 				},
+	// 				"state": "logged in",
+	// 				"wwpn": "10000000C9A1B2C3"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	hostPortsData := gjson.Get(hostPortsResp, "data").String()
+	hostPorts := gjson.Get(hostPortsData, "host_ports").Array()
+	for _, hostPort := range hostPorts {
+		labelvalues := []string{dClient.IpAddress, hostPort.Get("host.name").String(), hostPort.Get("wwpn").String()}
+		for _, m := range newStateMetrics(hostPortState, hostPort.Get("state").String(), hostPortStates, labelvalues...) {
+			ch <- m
+		}
+	}
+	log.Debugln("Leaving hosts collector.")
+	return lastErr
+}
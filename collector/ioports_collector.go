@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixIOPort    = "ds8k_ioport_"
+	ioPortInfoName  = "info"
+	ioPortStateName = "state"
+	ioPortSpeedName = "speed_bits_per_second"
+	ioPortInfoDesc  = "Information about the I/O port, value is always 1."
+	ioPortStateDesc = "The state of the I/O port, 1 for the current state and 0 for all others."
+	ioPortSpeedDesc = "The link speed of the I/O port in bits per second."
+)
+
+var (
+	ioPortInfo        *prometheus.Desc
+	ioPortState       *prometheus.Desc
+	ioPortSpeed       *prometheus.Desc
+	ioPortPerformance *ioPerformance
+	ioPortStates      = []string{"online", "offline", "fenced", "deconfigured"}
+)
+
+func init() {
+	registerCollector("ioport", defaultDisabled, NewIOPortCollector)
+	labelnames := []string{"target", "port"}
+	ioPortInfo = prometheus.NewDesc(prefixIOPort+ioPortInfoName, ioPortInfoDesc, append(labelnames, "wwpn", "type", "topology", "location"), nil)
+	ioPortState = prometheus.NewDesc(prefixIOPort+ioPortStateName, ioPortStateDesc, append(labelnames, "state"), nil)
+	ioPortSpeed = prometheus.NewDesc(prefixIOPort+ioPortSpeedName, ioPortSpeedDesc, labelnames, nil)
+	ioPortPerformance = newIOPerformance(prefixIOPort, "I/O port", labelnames)
+}
+
+// ioPortCollector collects host adapter I/O port metrics
+type ioPortCollector struct {
+}
+
+func NewIOPortCollector() (Collector, error) {
+	return &ioPortCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*ioPortCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ioPortInfo
+	ch <- ioPortState
+	ch <- ioPortSpeed
+	ioPortPerformance.Describe(ch)
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *ioPortCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering ioports collector ...")
+	reqIOPortURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/ioports"
+	ioPortsResp, err := dClient.CallDS8kAPI(ctx, reqIOPortURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/ioports' request failed: ", err)
+		return err
+	}
+	log.Debugln("Response of '/api/v1/ioports': ", ioPortsResp)
+	// This is a sample output of /api/v1/ioports call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 32,
+	// 		"total_counts": 32
+	// 	},
+	// 	"data": {
+	// 		"ioports": [
+	// 			{
+	// 				"id": "I0000",
+	// 				"link": {
+	// 					"href": "https:/10.23.1.10:8452/api/v1/ioports/I0000",
+	// 					"rel": "self"
+	// 				},
+	// 				"loc": "U1400.1B1.RJ55380-P1-C1-T0",
+	// 				"protocol": "SCSI-FCP",
+	// 				"speed": "16 Gb/s",
+	// 				"state": "online",
+	// 				"type": "Fibre Channel-SW",
+	// 				"wwpn": "50050763060812E0"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	ioPortsData := gjson.Get(ioPortsResp, "data").String()
+	ioPorts := gjson.Get(ioPortsData, "ioports").Array()
+	var lastErr error
+	for _, ioPort := range ioPorts {
+		portID := ioPort.Get("id").String()
+		labelvalues := []string{dClient.IpAddress, portID}
+		ch <- prometheus.MustNewConstMetric(ioPortInfo, prometheus.GaugeValue, 1, append(labelvalues, ioPort.Get("wwpn").String(), ioPort.Get("type").String(), ioPort.Get("protocol").String(), ioPort.Get("loc").String())...)
+		for _, m := range newStateMetrics(ioPortState, ioPort.Get("state").String(), ioPortStates, labelvalues...) {
+			ch <- m
+		}
+		if speed, ok := parseLinkSpeed(ioPort.Get("speed").String()); ok {
+			ch <- prometheus.MustNewConstMetric(ioPortSpeed, prometheus.GaugeValue, speed, labelvalues...)
+		}
+
+		if err := collectIOPerformance(ctx, dClient, "/api/v1/ioports/"+portID, ioPortPerformance, labelvalues, ch); err != nil {
+			lastErr = err
+		}
+	}
+	log.Debugln("Leaving ioports collector.")
+	return lastErr
+}
+
+// parseLinkSpeed converts a link speed such as "16 Gb/s" to bits per second.
+func parseLinkSpeed(speed string) (float64, bool) {
+	fields := strings.Fields(speed)
+	if len(fields) != 2 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToLower(fields[1]) {
+	case "gb/s":
+		return value * 1e9, true
+	case "mb/s":
+		return value * 1e6, true
+	}
+	return 0, false
+}
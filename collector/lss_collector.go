@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixLSS         = "ds8k_lss_"
+	lssInfoName       = "info"
+	lssVolumesName    = "volumes"
+	lssCapacityName   = "capacity_bytes"
+	lssPAVAliasesName = "pav_aliases"
+	lssInfoDesc       = "Information about the logical subsystem (LSS) or CKD logical control unit (LCU), value is always 1."
+	lssVolumesDesc    = "The number of volumes configured in the LSS, without PAV aliases."
+	lssCapacityDesc   = "The configured capacity of the volumes in the LSS in bytes."
+	lssPAVAliasesDesc = "The number of PAV alias volumes configured in the CKD LCU."
+	stgtypeCKD        = "ckd"
+)
+
+var (
+	lssInfo       *prometheus.Desc
+	lssVolumes    *prometheus.Desc
+	lssCapacity   *prometheus.Desc
+	lssPAVAliases *prometheus.Desc
+)
+
+func init() {
+	registerCollector("lss", defaultDisabled, NewLSSCollector)
+	labelnames := []string{"target", "lss"}
+	lssInfo = prometheus.NewDesc(prefixLSS+lssInfoName, lssInfoDesc, append(labelnames, "type", "group", "ssid"), nil)
+	lssVolumes = prometheus.NewDesc(prefixLSS+lssVolumesName, lssVolumesDesc, labelnames, nil)
+	lssCapacity = prometheus.NewDesc(prefixLSS+lssCapacityName, lssCapacityDesc, labelnames, nil)
+	lssPAVAliases = prometheus.NewDesc(prefixLSS+lssPAVAliasesName, lssPAVAliasesDesc, labelnames, nil)
+}
+
+// lssCollector collects LSS and LCU metrics
+type lssCollector struct {
+}
+
+func NewLSSCollector() (Collector, error) {
+	return &lssCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*lssCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lssInfo
+	ch <- lssVolumes
+	ch <- lssCapacity
+	ch <- lssPAVAliases
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *lssCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering lss collector ...")
+	reqLSSURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/lss"
+	lssResp, err := dClient.CallDS8kAPI(ctx, reqLSSURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/lss' request failed: ", err)
+		return err
+	}
+	log.Debugln("Response of '/api/v1/lss': ", lssResp)
+	// This is a sample output of /api/v1/lss call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 2,
+	// 		"total_counts": 2
+	// 	},
+	// 	"data": {
+	// 		"lss": [
+	// 			{
+	// 				"addrgrp": "0",
+	// 				"configvols": "12",
+	// 				"group": "0",
+	// 				"id": "00",
+	// 				"link": {
+	// 					"href": "https:/10.23.1.10:8452/api/v1/lss/00",
+	// 					"rel": "self"
+	// 				},
+	// 				"type": "fb"
+	// 			},
+	// 			{
+	// 				"addrgrp": "8",
+	// 				"configvols": "64",
+	// 				"group": "0",
+	// 				"id": "80",
+	// 				"sub_system_identifier": "FF80",
+	// 				"type": "ckd"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	lssData := gjson.Get(lssResp, "data").String()
+	lsss := gjson.Get(lssData, "lss").Array()
+	var lastErr error
+	for _, lss := range lsss {
+		lssID := lss.Get("id").String()
+		labelvalues := []string{dClient.IpAddress, lssID}
+
+		reqVolumeURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/lss/" + lssID + "/volumes"
+		volumesResp, err := dClient.CallDS8kAPI(ctx, reqVolumeURL)
+		if err != nil {
+			log.Errorln("Executing '/api/v1/lss/"+lssID+"/volumes' request failed: ", err)
+			lastErr = err
+			// The type is derived from the volumes, the info is skipped
+			// rather than exposed with a different type label.
+			continue
+		}
+		log.Debugln("Response of '/api/v1/lss/"+lssID+"/volumes': ", volumesResp)
+		// This is a sample output of /api/v1/lss/lssID/volumes call of a CKD LCU
+		// {
+		// 	"counts": {
+		// 		"data_counts": 2,
+		// 		"total_counts": 2
+		// 	},
+		// 	"data": {
+		// 		"volumes": [
+		// 			{
+		// 				"cap": "8513323008",
+		// 				"datatype": "3390",
+		// 				"id": "8000",
+		// 				"lss": {
+		// 					"id": "80"
+		// 				},
+		// 				"name": "prod_8000",
+		// 				"pool": {
+		// 					"id": "P1"
+		// 				},
+		// 				"state": "normal",
+		// 				"stgtype": "ckd"
+		// 			},
+		// 			{
+		// 				"cap": "0",
+		// 				"datatype": "3390",
+		// 				"id": "80FF",
+		// 				"lss": {
+		// 					"id": "80"
+		// 				},
+		// 				"name": "",
+		// 				"state": "normal",
+		// 				"stgtype": "ckd"
+		// 			}
+		// 		]
+		// 	},
+		// 	"server": {
+		// 		"code": "",
+		// 		"message": "Operation done successfully.",
+		// 		"status": "ok"
+		// 	}
+		// }
+		volumesData := gjson.Get(volumesResp, "data").String()
+		volumes := gjson.Get(volumesData, "volumes").Array()
+		// The LSS is FB or CKD like its volumes, an LSS without volumes
+		// has only the type of the LSS itself.
+		lssType := lss.Get("type").String()
+		var count, capacity, aliases float64
+		for _, volume := range volumes {
+			stgtype := volume.Get("stgtype").String()
+			if stgtype != "" {
+				lssType = stgtype
+			}
+			// PAV aliases address the extents of a base volume, so unlike
+			// base volumes they are not allocated from an extent pool.
+			if stgtype == stgtypeCKD && volume.Get("pool.id").String() == "" {
+				aliases++
+				continue
+			}
+			count++
+			capacity += volume.Get("cap").Float()
+		}
+		ch <- prometheus.MustNewConstMetric(lssInfo, prometheus.GaugeValue, 1, append(labelvalues, lssType, lss.Get("group").String(), lss.Get("sub_system_identifier").String())...)
+		ch <- prometheus.MustNewConstMetric(lssVolumes, prometheus.GaugeValue, count, labelvalues...)
+		ch <- prometheus.MustNewConstMetric(lssCapacity, prometheus.GaugeValue, capacity, labelvalues...)
+		if lssType == stgtypeCKD {
+			ch <- prometheus.MustNewConstMetric(lssPAVAliases, prometheus.GaugeValue, aliases, labelvalues...)
+		}
+	}
+	log.Debugln("Leaving lss collector.")
+	return lastErr
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixNode    = "ds8k_node_"
+	nodeInfoName  = "info"
+	nodeStateName = "state"
+	nodeRoleName  = "role"
+	nodeInfoDesc  = "Information about the node (CEC), value is always 1."
+	nodeStateDesc = "The state of the node (CEC), 1 for the current state and 0 for all others."
+	nodeRoleDesc  = "The role of the node (CEC), 1 for the current role and 0 for all others."
+)
+
+var (
+	nodeInfo   *prometheus.Desc
+	nodeState  *prometheus.Desc
+	nodeRole   *prometheus.Desc
+	nodeStates = []string{"online", "offline", "fenced", "service"}
+	// A node that runs alone after a failover is "standalone".
+	nodeRoles = []string{"primary", "secondary", "standalone"}
+)
+
+func init() {
+	registerCollector("node", defaultDisabled, NewNodeCollector)
+	labelnames := []string{"target", "node"}
+	nodeInfo = prometheus.NewDesc(prefixNode+nodeInfoName, nodeInfoDesc, append(labelnames, "name", "location"), nil)
+	nodeState = prometheus.NewDesc(prefixNode+nodeStateName, nodeStateDesc, append(labelnames, "state"), nil)
+	nodeRole = prometheus.NewDesc(prefixNode+nodeRoleName, nodeRoleDesc, append(labelnames, "role"), nil)
+}
+
+// nodeCollector collects storage server (CEC) metrics
+type nodeCollector struct {
+}
+
+func NewNodeCollector() (Collector, error) {
+	return &nodeCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*nodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nodeInfo
+	ch <- nodeState
+	ch <- nodeRole
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *nodeCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering nodes collector ...")
+	reqNodeURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/nodes"
+	nodesResp, err := dClient.CallDS8kAPI(ctx, reqNodeURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/nodes' request failed: ", err)
+		return err
+	}
+	log.Debugln("Response of '/api/v1/nodes': ", nodesResp)
+	// This is a sample output of /api/v1/nodes call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 2,
+	// 		"total_counts": 2
+	// 	},
+	// 	"data": {
+	// 		"nodes": [
+	// 			{
+	// 				"id": "00",
+	// 				"link": {
+	// 					"href": "https:/10.23.1.10:8452/api/v1/nodes/00",
+	// 					"rel": "self"
+	// 				},
+	// 				"loc": "U8286.42A.1234560",
+	// 				"name": "ServerA",
+	// 				"role": "primary",
+	// 				"state": "online"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	nodesData := gjson.Get(nodesResp, "data").String()
+	nodes := gjson.Get(nodesData, "nodes").Array()
+	for _, node := range nodes {
+		labelvalues := []string{dClient.IpAddress, node.Get("id").String()}
+		ch <- prometheus.MustNewConstMetric(nodeInfo, prometheus.GaugeValue, 1, append(labelvalues, node.Get("name").String(), node.Get("loc").String())...)
+		for _, m := range newStateMetrics(nodeState, node.Get("state").String(), nodeStates, labelvalues...) {
+			ch <- m
+		}
+		for _, m := range newStateMetrics(nodeRole, node.Get("role").String(), nodeRoles, labelvalues...) {
+			ch <- m
+		}
+	}
+	log.Debugln("Leaving nodes collector.")
+	return nil
+}
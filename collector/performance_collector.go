@@ -1,14 +1,21 @@
 package collector
 
 import (
-	"regexp"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/tidwall/gjson"
 	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
@@ -16,31 +23,58 @@ const (
 	readIOName        = "read"
 	writeIOName       = "write"
 	totalIOName       = "total"
+	responseTimeName  = "response_time_seconds"
 	readIODesc        = "The average number of I/O operations that are transferred per second for read operations to Systems during the sample period."
 	writeIODesc       = "The average number of I/O operations that are transferred per second for write operations to Systems during the sample period."
 	totalIODesc       = "The average number of I/O operations that are transferred per second for read and write operations to Systems during the sample period."
+	responseTimeDesc  = "The average response time in seconds of I/O operations to Systems during the sample period."
+	iopsName          = "iops"
+	throughputName    = "throughput_bytes_per_second"
+
+	defaultPerformanceInterval = time.Minute
+	defaultPerformanceWindow   = time.Minute
+	// maxPerformanceBackfill limits how far back missing samples are requested.
+	maxPerformanceBackfill = time.Hour
 )
 
 var (
-	read  *prometheus.Desc
-	write *prometheus.Desc
-	total *prometheus.Desc
+	performanceRanks        = kingpin.Flag("collector.performance.ranks", "Collect the performance of every rank, which needs one request per rank.").Default("false").Bool()
+	performanceBackfillFile = kingpin.Flag("collector.performance.backfill-file", "File to append performance samples to that are older than the exposed ones, in the OpenMetrics format.").String()
+	// lastSampleTimes holds the time of the last performance sample ingested per resource.
+	lastSampleTimes sync.Map
+	backfillMutex   sync.Mutex
+
+	read                  *prometheus.Desc
+	write                 *prometheus.Desc
+	total                 *prometheus.Desc
+	responseTime          *prometheus.Desc
+	performanceLabelnames = []string{"resource", "target"}
+	poolPerformance       *ioPerformance
+	rankPerformance       *ioPerformance
 )
 
 func init() {
 	registerCollector("performance", defaultEnabled, NewPerformanceCollector)
-	labelnames := []string{"resource", "target"}
-	read = prometheus.NewDesc(prefixPerformance+readIOName, readIODesc, labelnames, nil)
-	write = prometheus.NewDesc(prefixPerformance+writeIOName, writeIODesc, labelnames, nil)
-	total = prometheus.NewDesc(prefixPerformance+totalIOName, totalIODesc, labelnames, nil)
+	read = prometheus.NewDesc(prefixPerformance+readIOName, readIODesc, performanceLabelnames, nil)
+	write = prometheus.NewDesc(prefixPerformance+writeIOName, writeIODesc, performanceLabelnames, nil)
+	total = prometheus.NewDesc(prefixPerformance+totalIOName, totalIODesc, performanceLabelnames, nil)
+	responseTime = prometheus.NewDesc(prefixPerformance+responseTimeName, responseTimeDesc, append(performanceLabelnames, "op"), nil)
+	poolPerformance = newIOPerformance(prefixPerformance+"pool_", "pool", []string{"target", "pool"})
+	rankPerformance = newIOPerformance(prefixPerformance+"rank_", "rank", []string{"target", "rank", "pool"})
 }
 
 // poolCollector collects system metrics
 type performanceCollector struct {
+	volumes *volumeSelector
 }
 
 func NewPerformanceCollector() (Collector, error) {
-	return &performanceCollector{}, nil
+	if volumeSelection == nil {
+		if err := ValidateFlags(); err != nil {
+			return nil, err
+		}
+	}
+	return &performanceCollector{volumes: volumeSelection}, nil
 }
 
 //Describe describes the metrics
@@ -48,15 +82,21 @@ func (*performanceCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- read
 	ch <- write
 	ch <- total
+	ch <- responseTime
+	poolPerformance.Describe(ch)
+	rankPerformance.Describe(ch)
+	volumePerformance.Describe(ch)
 }
 
 //Collect collects metrics from DS8k Restful API
-func (c *performanceCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus.Metric) {
+func (c *performanceCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
 	log.Debugln("Entering performance collector ...")
+	var lastErr error
 	reqSystemURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/systems"
-	systemsResp, err := dClient.CallDS8kAPI(reqSystemURL)
+	systemsResp, err := dClient.CallDS8kAPI(ctx, reqSystemURL)
 	if err != nil {
 		log.Errorf("Executing /api/v1/systems request failed: %s", err)
+		lastErr = err
 	}
 	log.Debugln("Response of '/api/v1/systems': ", systemsResp)
 	//This is the sample output of /api/v1/systems call
@@ -94,27 +134,16 @@ func (c *performanceCollector) Collect(dClient utils.DS8kClient, ch chan<- prome
 	systems := gjson.Get(systemsData, "systems").Array()
 	for _, system := range systems {
 		serial_number := system.Get("sn").String()
-		location, err := time.LoadLocation(dClient.Location)
-		log.Debugln("The timezone of location is %s", location)
-		//Examples of dClient.Location: America/New_York ; America/Los_Angeles
+		key := dClient.IpAddress + "/systems/" + serial_number
+		query := performanceQuery(performanceWindow(dClient, key))
+		reqPerformanceURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/systems/" + serial_number + "/performance" + query
+		performanceInfo, err := dClient.CallDS8kAPI(ctx, reqPerformanceURL)
 		if err != nil {
-			log.Errorln("Loading location of device failed: ", err)
+			log.Errorln("Executing '/api/v1/systems/"+serial_number+"/performance"+query+"' request failed: ", err)
+			lastErr = err
+			continue
 		}
-
-		deviceTime := time.Now().In(location) //Get ds8k's location time.  Example: 2019-07-09 23:20:47.890562 -0400 EDT
-		log.Debugln(" ds8k's local time is ", deviceTime)
-		timeZone := regexp.MustCompile(`\+|\-\d{4}`).FindString(deviceTime.String()) // Get timezone from devicetime. Example: -0400
-		duration, _ := time.ParseDuration("-1m")                                     //Roll back 1 minute
-		beforeTime := deviceTime.Add(duration)
-		afterTime := beforeTime.Add(duration)
-		beforeTimeFormat := strings.Replace(regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`).FindString(beforeTime.String()), " ", "T", -1) // Get time form devicetime. Example: 2019-07-09 23:20:47
-		afterTimeFormat := strings.Replace(regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`).FindString(afterTime.String()), " ", "T", -1)
-		reqPerformanceURL := "https://" + dClient.IpAddress + ":8452/api/v1/systems/" + serial_number + "/performance?after=" + afterTimeFormat + timeZone + "&before=" + beforeTimeFormat + timeZone
-		performanceInfo, err := dClient.CallDS8kAPI(reqPerformanceURL)
-		if err != nil {
-			log.Errorln("Executing '/api/v1/systems/"+serial_number+"/performance?after="+afterTimeFormat+timeZone+"&before="+beforeTimeFormat+timeZone+"' request failed: ", err)
-		}
-		log.Debugln("Response of '/api/v1/systems/"+serial_number+"/performance?after="+afterTimeFormat+timeZone+"&before="+beforeTimeFormat+timeZone+"' : ", performanceInfo)
+		log.Debugln("Response of '/api/v1/systems/"+serial_number+"/performance"+query+"' : ", performanceInfo)
 		// This is the sample output of /api/v1/systems/performances?after=afterTime&before=beforeTime call
 		// {
 		// 	"counts": {
@@ -147,16 +176,357 @@ func (c *performanceCollector) Collect(dClient utils.DS8kClient, ch chan<- prome
 
 		performanceData := gjson.Get(performanceInfo, "data").String()
 		performances := gjson.Get(performanceData, "performance").Array()
-		labelvalues := []string{system.Get("name").String(), dClient.IpAddress}
-		if len(performances) > 0 {
-			IOPS := performances[0].Get("IOPS")
-			ch <- prometheus.MustNewConstMetric(read, prometheus.GaugeValue, IOPS.Get("read").Float(), labelvalues...)
-			ch <- prometheus.MustNewConstMetric(write, prometheus.GaugeValue, IOPS.Get("write").Float(), labelvalues...)
-			ch <- prometheus.MustNewConstMetric(total, prometheus.GaugeValue, IOPS.Get("total").Float(), labelvalues...)
-		} else {
+		if len(performances) == 0 {
 			log.Errorln("Metric of performance is null")
+			continue
 		}
+		labelvalues := []string{system.Get("name").String(), dClient.IpAddress}
+		responseTimeLabelnames := append(append([]string{}, performanceLabelnames...), "op")
+		withOp := func(op string) []string {
+			return append(append([]string{}, labelvalues...), op)
+		}
+		emitPerformanceSamples(dClient, key, performances, ch, func(sample gjson.Result) []performanceValue {
+			IOPS := sample.Get("IOPS")
+			values := []performanceValue{
+				{read, prefixPerformance + readIOName, performanceLabelnames, labelvalues, IOPS.Get("read").Float()},
+				{write, prefixPerformance + writeIOName, performanceLabelnames, labelvalues, IOPS.Get("write").Float()},
+				{total, prefixPerformance + totalIOName, performanceLabelnames, labelvalues, IOPS.Get("total").Float()},
+			}
+			// The DS8K reports response times in milliseconds.
+			for _, op := range []string{"read", "write", "average"} {
+				values = append(values, performanceValue{responseTime, prefixPerformance + responseTimeName, responseTimeLabelnames, withOp(op), sample.Get("responseTime."+op).Float() / 1000})
+			}
+			return values
+		})
+	}
 
+	pools, err := c.collectPoolPerformance(ctx, dClient, ch)
+	if err != nil {
+		lastErr = err
+	}
+	if *performanceRanks {
+		if err := c.collectRankPerformance(ctx, dClient, pools, ch); err != nil {
+			lastErr = err
+		}
+	}
+	if *performanceVolumes {
+		if err := c.collectVolumePerformance(ctx, dClient, pools, ch); err != nil {
+			lastErr = err
+		}
 	}
 	log.Debugln("Leaving performance collector.")
+	return lastErr
+}
+
+// collectPoolPerformance collects the performance of every pool and returns
+// the pools.
+func (c *performanceCollector) collectPoolPerformance(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) ([]gjson.Result, error) {
+	reqPoolURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/pools"
+	poolsResp, err := dClient.CallDS8kAPI(ctx, reqPoolURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/pools' request failed: ", err)
+		return nil, err
+	}
+	poolsData := gjson.Get(poolsResp, "data").String()
+	pools := gjson.Get(poolsData, "pools").Array()
+	var lastErr error
+	for _, pool := range pools {
+		poolID := pool.Get("id").String()
+		if err := collectIOPerformance(ctx, dClient, "/api/v1/pools/"+poolID, poolPerformance, []string{dClient.IpAddress, pool.Get("name").String() + "_" + poolID}, ch); err != nil {
+			lastErr = err
+		}
+	}
+	return pools, lastErr
+}
+
+// collectRankPerformance collects the performance of every rank, labelled with
+// the pool the rank is assigned to.
+func (c *performanceCollector) collectRankPerformance(ctx context.Context, dClient utils.DS8kClient, pools []gjson.Result, ch chan<- prometheus.Metric) error {
+	poolNames := make(map[string]string)
+	for _, pool := range pools {
+		poolNames[pool.Get("id").String()] = pool.Get("name").String() + "_" + pool.Get("id").String()
+	}
+	reqRankURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/ranks"
+	ranksResp, err := dClient.CallDS8kAPI(ctx, reqRankURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/ranks' request failed: ", err)
+		return err
+	}
+	log.Debugln("Response of '/api/v1/ranks': ", ranksResp)
+	// This is a sample output of /api/v1/ranks call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"ranks": [
+	// 			{
+	// 				"array": {
+	// 					"id": "A0"
+	// 				},
+	// 				"id": "R0",
+	// 				"pool": {
+	// 					"id": "P0"
+	// 				},
+	// 				"raidtype": "6",
+	// 				"state": "normal",
+	// 				"stgtype": "fb"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+	ranksData := gjson.Get(ranksResp, "data").String()
+	ranks := gjson.Get(ranksData, "ranks").Array()
+	var lastErr error
+	for _, rank := range ranks {
+		rankID := rank.Get("id").String()
+		if err := collectIOPerformance(ctx, dClient, "/api/v1/ranks/"+rankID, rankPerformance, []string{dClient.IpAddress, rankID, poolNames[rank.Get("pool.id").String()]}, ch); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// ioPerformance describes the IOPS, throughput and response time metrics of
+// one kind of resource, labelled by operation.
+type ioPerformance struct {
+	prefix       string
+	labelnames   []string
+	iops         *prometheus.Desc
+	throughput   *prometheus.Desc
+	responseTime *prometheus.Desc
+}
+
+func newIOPerformance(prefix string, resource string, labelnames []string) *ioPerformance {
+	opLabelnames := append(append([]string{}, labelnames...), "op")
+	return &ioPerformance{
+		prefix:       prefix,
+		labelnames:   opLabelnames,
+		iops:         prometheus.NewDesc(prefix+iopsName, fmt.Sprintf("The average number of I/O operations that are transferred per second through the %s during the sample period.", resource), opLabelnames, nil),
+		throughput:   prometheus.NewDesc(prefix+throughputName, fmt.Sprintf("The average number of bytes that are transferred per second through the %s during the sample period.", resource), opLabelnames, nil),
+		responseTime: prometheus.NewDesc(prefix+responseTimeName, fmt.Sprintf("The average response time in seconds of I/O operations through the %s during the sample period.", resource), opLabelnames, nil),
+	}
+}
+
+// Describe describes the metrics.
+func (p *ioPerformance) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.iops
+	ch <- p.throughput
+	ch <- p.responseTime
+}
+
+// values returns the values of one performance sample.
+func (p *ioPerformance) values(sample gjson.Result, labelvalues []string) []performanceValue {
+	var values []performanceValue
+	withOp := func(op string) []string {
+		return append(append([]string{}, labelvalues...), op)
+	}
+	for _, op := range []string{"read", "write", "total"} {
+		values = append(values, performanceValue{p.iops, p.prefix + iopsName, p.labelnames, withOp(op), sample.Get("IOPS." + op).Float()})
+		// The DS8K reports throughput in MB/s.
+		values = append(values, performanceValue{p.throughput, p.prefix + throughputName, p.labelnames, withOp(op), sample.Get("throughput."+op).Float() * 1024 * 1024})
+	}
+	// The DS8K reports response times in milliseconds.
+	for _, op := range []string{"read", "write", "average"} {
+		values = append(values, performanceValue{p.responseTime, p.prefix + responseTimeName, p.labelnames, withOp(op), sample.Get("responseTime."+op).Float() / 1000})
+	}
+	return values
+}
+
+// collectIOPerformance requests the performance samples of the resource at
+// path, for example /api/v1/pools/P0, and exposes them as perf.
+func collectIOPerformance(ctx context.Context, dClient utils.DS8kClient, path string, perf *ioPerformance, labelvalues []string, ch chan<- prometheus.Metric) error {
+	key, performances, err := fetchPerformance(ctx, dClient, path)
+	if len(performances) == 0 {
+		return err
+	}
+	emitPerformanceSamples(dClient, key, performances, ch, func(sample gjson.Result) []performanceValue {
+		return perf.values(sample, labelvalues)
+	})
+	return nil
+}
+
+// fetchPerformance requests the performance samples of the resource at path
+// and returns them together with the key of the resource.
+func fetchPerformance(ctx context.Context, dClient utils.DS8kClient, path string) (key string, performances []gjson.Result, err error) {
+	key = dClient.IpAddress + strings.TrimPrefix(path, "/api/v1")
+	query := performanceQuery(performanceWindow(dClient, key))
+	reqPerformanceURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + path + "/performance" + query
+	performanceInfo, err := dClient.CallDS8kAPI(ctx, reqPerformanceURL)
+	if err != nil {
+		log.Errorln("Executing '"+path+"/performance"+query+"' request failed: ", err)
+		return key, nil, err
+	}
+	log.Debugln("Response of '"+path+"/performance"+query+"' : ", performanceInfo)
+	// This is the sample output of the {path}/performance?after=afterTime&before=beforeTime call of I/O ports, pools, ranks and volumes
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"performance": [
+	// 			{
+	// 				"IOPS": {
+	// 					"read": "120.5",
+	// 					"total": "310.25",
+	// 					"write": "189.75"
+	// 				},
+	// 				"performancesampletime": "2019-05-20T01:44:42-0400",
+	// 				"responseTime": {
+	// 					"average": "0.31",
+	// 					"read": "0.22",
+	// 					"write": "0.37"
+	// 				},
+	// 				"throughput": {
+	// 					"read": "7.53",
+	// 					"total": "19.39",
+	// 					"write": "11.86"
+	// 				}
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	performanceData := gjson.Get(performanceInfo, "data").String()
+	performances = gjson.Get(performanceData, "performance").Array()
+	if len(performances) == 0 {
+		log.Debugf("No performance sample for %s", path)
+	}
+	return key, performances, nil
+}
+
+// performanceWindow returns the window of performance samples to request for
+// the resource identified by key. The window ends one sample interval ago, so
+// the last sample is complete, and reaches back to the last sample ingested of
+// the resource, so no sample is lost between two collections.
+func performanceWindow(dClient utils.DS8kClient, key string) (after time.Time, before time.Time) {
+	interval := defaultPerformanceInterval
+	if dClient.PerformanceInterval > 0 {
+		interval = dClient.PerformanceInterval
+	}
+	window := defaultPerformanceWindow
+	if dClient.PerformanceWindow > 0 {
+		window = dClient.PerformanceWindow
+	}
+	deviceTime := dClient.DeviceTime()
+	log.Debugln(" ds8k's local time is ", deviceTime)
+	before = deviceTime.Add(-interval)
+	after = before.Add(-window)
+	if last, ok := lastSampleTimes.Load(key); ok && last.(time.Time).Before(after) {
+		after = last.(time.Time)
+		if before.Sub(after) > maxPerformanceBackfill {
+			after = before.Add(-maxPerformanceBackfill)
+		}
+	}
+	return after.In(deviceTime.Location()), before
+}
+
+// performanceQuery returns the after/before query string selecting the
+// performance samples in the window, in the device's zone.
+func performanceQuery(after time.Time, before time.Time) string {
+	query := url.Values{}
+	query.Set("after", after.Format(ds8kTimeLayout))
+	query.Set("before", before.Format(ds8kTimeLayout))
+	return "?" + query.Encode()
+}
+
+// performanceValue is one value of a performance sample.
+type performanceValue struct {
+	desc        *prometheus.Desc
+	name        string
+	labelnames  []string
+	labelvalues []string
+	value       float64
+}
+
+// emitPerformanceSamples exposes the newest of the samples with its original
+// timestamp. Older samples that were not ingested before are appended to the
+// backfill file, if one is configured.
+func emitPerformanceSamples(dClient utils.DS8kClient, key string, samples []gjson.Result, ch chan<- prometheus.Metric, values func(gjson.Result) []performanceValue) {
+	type timedSample struct {
+		time   time.Time
+		sample gjson.Result
+	}
+	var timed []timedSample
+	for _, sample := range samples {
+		sampleTime, err := time.Parse(ds8kTimeLayout, sample.Get("performancesampletime").String())
+		if err != nil {
+			log.Errorln("Parsing performance sample time failed: ", err)
+			continue
+		}
+		timed = append(timed, timedSample{sampleTime, sample})
+	}
+	if len(timed) == 0 {
+		return
+	}
+	sort.Slice(timed, func(i, j int) bool { return timed[i].time.Before(timed[j].time) })
+	newest := timed[len(timed)-1]
+	utils.SetDeviceZone(dClient.IpAddress, newest.time)
+
+	// The newest sample is always exposed, so every scraper gets it.
+	for _, v := range values(newest.sample) {
+		ch <- prometheus.NewMetricWithTimestamp(newest.time, prometheus.MustNewConstMetric(v.desc, prometheus.GaugeValue, v.value, v.labelvalues...))
+	}
+
+	var last time.Time
+	if l, ok := lastSampleTimes.Load(key); ok {
+		last = l.(time.Time)
+	}
+	if !newest.time.After(last) {
+		return
+	}
+	lastSampleTimes.Store(key, newest.time)
+	if last.IsZero() {
+		return
+	}
+	for _, t := range timed[:len(timed)-1] {
+		if !t.time.After(last) {
+			continue
+		}
+		if *performanceBackfillFile == "" {
+			log.Debugf("Skipping performance sample of %s at %s, no backfill file configured", key, t.time)
+			continue
+		}
+		if err := writeBackfill(t.time, values(t.sample)); err != nil {
+			log.Errorln("Writing performance backfill failed: ", err)
+		}
+	}
+}
+
+// writeBackfill appends the values of one sample to the backfill file. Append
+// "# EOF" to a copy of the file before importing it with
+// "promtool tsdb create-blocks-from openmetrics".
+func writeBackfill(sampleTime time.Time, values []performanceValue) error {
+	var b strings.Builder
+	for _, v := range values {
+		var labels []string
+		for i, name := range v.labelnames {
+			labels = append(labels, name+"="+strconv.Quote(v.labelvalues[i]))
+		}
+		fmt.Fprintf(&b, "%s{%s} %s %d\n", v.name, strings.Join(labels, ","), strconv.FormatFloat(v.value, 'g', -1, 64), sampleTime.Unix())
+	}
+
+	backfillMutex.Lock()
+	defer backfillMutex.Unlock()
+	f, err := os.OpenFile(*performanceBackfillFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
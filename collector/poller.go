@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+var lastSuccessDesc *prometheus.Desc
+
+func init() {
+	lastSuccessDesc = prometheus.NewDesc(prefix+"last_successful_collection_timestamp_seconds", "Unix timestamp of the last successful collection of a collector for one resource", []string{"target", "collector"}, nil)
+}
+
+// Poller collects every target in the background on its own interval and
+// keeps the last collected metrics of each target in memory.
+type Poller struct {
+	collector *DS8kCollector
+	targets   []utils.Targets
+	interval  time.Duration
+
+	mu        sync.RWMutex
+	snapshots map[string]*snapshot
+}
+
+// snapshot holds the result of the last collection of one target.
+type snapshot struct {
+	metrics     []prometheus.Metric
+	lastSuccess map[string]time.Time
+}
+
+// NewPoller creates a new Poller. Targets without an interval of their own
+// are collected every interval.
+func NewPoller(targets []utils.Targets, interval time.Duration) (*Poller, error) {
+	dsc, err := NewDS8kCollector(context.Background(), targets)
+	if err != nil {
+		return nil, err
+	}
+	return &Poller{
+		collector: dsc,
+		targets:   targets,
+		interval:  interval,
+		snapshots: make(map[string]*snapshot),
+	}, nil
+}
+
+// Start launches one polling goroutine per target.
+func (p *Poller) Start() {
+	log.Infof("Enabled collectors:")
+	for n := range p.collector.Collectors {
+		log.Infof(" - %s", n)
+	}
+	for _, t := range p.targets {
+		interval := p.interval
+		if t.Interval > 0 {
+			interval = t.Interval
+		}
+		log.Infof("Polling %s every %s", t.IpAddress, interval)
+		go p.run(t, interval)
+	}
+}
+
+func (p *Poller) run(target utils.Targets, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.poll(target, interval)
+		<-ticker.C
+	}
+}
+
+// poll collects one target and replaces its snapshot. A collection taking
+// longer than interval is stopped, so the next poll starts on time and the
+// snapshot reports the timeout.
+func (p *Poller) poll(target utils.Targets, interval time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), interval)
+	defer cancel()
+	ch := make(chan prometheus.Metric)
+	done := make(chan []string, 1)
+	go func() {
+		done <- p.collector.collectTarget(ctx, target, ch)
+		close(ch)
+	}()
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+	succeeded := <-done
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lastSuccess := make(map[string]time.Time)
+	if old, ok := p.snapshots[target.IpAddress]; ok {
+		for name, t := range old.lastSuccess {
+			lastSuccess[name] = t
+		}
+	}
+	for _, name := range succeeded {
+		lastSuccess[name] = now
+	}
+	p.snapshots[target.IpAddress] = &snapshot{metrics: metrics, lastSuccess: lastSuccess}
+}
+
+// Collector returns a prometheus.Collector serving the last snapshots of the
+// given targets.
+func (p *Poller) Collector(targets ...utils.Targets) prometheus.Collector {
+	return &snapshotCollector{poller: p, targets: targets}
+}
+
+// snapshotCollector implements the prometheus.Collector interface on top of
+// the snapshots of a Poller.
+type snapshotCollector struct {
+	poller  *Poller
+	targets []utils.Targets
+}
+
+// Describe implements the Prometheus.Collector interface.
+func (c *snapshotCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lastSuccessDesc
+	c.poller.collector.Describe(ch)
+}
+
+// Collect implements the Prometheus.Collector interface.
+func (c *snapshotCollector) Collect(ch chan<- prometheus.Metric) {
+	c.poller.mu.RLock()
+	snapshots := make(map[string]*snapshot)
+	for _, t := range c.targets {
+		if s, ok := c.poller.snapshots[t.IpAddress]; ok {
+			snapshots[t.IpAddress] = s
+		} else {
+			log.Debugf("No snapshot collected yet for %s", t.IpAddress)
+		}
+	}
+	c.poller.mu.RUnlock()
+
+	for target, s := range snapshots {
+		for _, m := range s.metrics {
+			ch <- m
+		}
+		for name, last := range s.lastSuccess {
+			ch <- prometheus.MustNewConstMetric(lastSuccessDesc, prometheus.GaugeValue, float64(last.Unix()), target, name)
+		}
+	}
+}
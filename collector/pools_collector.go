@@ -1,6 +1,8 @@
 package collector
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/tidwall/gjson"
@@ -16,12 +18,39 @@ const totalPoolCapacityDesc = "The total capacity of pool"
 const availablePoolCapacityDesc = "The avaliable capacity of pool"
 const allocatedPoolCapacityDesc = "The allocated capacity of pool"
 const poolCapacityUsedPercentDesc = "The pool capacity utilization."
+const poolInfoName = "info"
+const poolTierCapacityName = "tier_capacity_bytes"
+const poolTierAllocatedName = "tier_allocated_bytes"
+const poolAlertThresholdName = "alert_threshold_ratio"
+const poolOverprovisionedName = "overprovisioned_ratio"
+const poolESERealAllocatedName = "ese_real_capacity_allocated_bytes"
+const poolESEVirtualAllocatedName = "ese_virtual_capacity_allocated_bytes"
+const poolRepositoryCapacityName = "repository_capacity_bytes"
+const poolRepositoryAllocatedName = "repository_allocated_bytes"
+const poolInfoDesc = "Information about the pool, value is always 1."
+const poolTierCapacityDesc = "The capacity of the storage tier in the pool in bytes."
+const poolTierAllocatedDesc = "The allocated capacity of the storage tier in the pool in bytes."
+const poolAlertThresholdDesc = "An alert is raised when the available capacity of the pool falls below this ratio of its total capacity."
+const poolOverprovisionedDesc = "The ratio of the virtual capacity of the volumes to the real capacity of the pool."
+const poolESERealAllocatedDesc = "The real capacity allocated to extent space efficient volumes in the pool in bytes."
+const poolESEVirtualAllocatedDesc = "The virtual capacity of extent space efficient volumes in the pool in bytes."
+const poolRepositoryCapacityDesc = "The capacity of the space efficient repository of the pool in bytes."
+const poolRepositoryAllocatedDesc = "The allocated capacity of the space efficient repository of the pool in bytes."
 
 var (
 	totalPoolCapacity       *prometheus.Desc
 	availablePoolCapacity   *prometheus.Desc
 	allocatedPoolCapacity   *prometheus.Desc
 	poolCapacityUsedPercent *prometheus.Desc
+	poolInfo                *prometheus.Desc
+	poolTierCapacity        *prometheus.Desc
+	poolTierAllocated       *prometheus.Desc
+	poolAlertThreshold      *prometheus.Desc
+	poolOverprovisioned     *prometheus.Desc
+	poolESERealAllocated    *prometheus.Desc
+	poolESEVirtualAllocated *prometheus.Desc
+	poolRepositoryCapacity  *prometheus.Desc
+	poolRepositoryAllocated *prometheus.Desc
 )
 
 func init() {
@@ -31,6 +60,15 @@ func init() {
 	availablePoolCapacity = prometheus.NewDesc(prefixPool+availablePoolCapacityName, availablePoolCapacityDesc, labelnames, nil)
 	allocatedPoolCapacity = prometheus.NewDesc(prefixPool+allocatedPoolCapacityName, allocatedPoolCapacityDesc, labelnames, nil)
 	poolCapacityUsedPercent = prometheus.NewDesc(prefixPool+poolCapacityUsedPercentName, poolCapacityUsedPercentDesc, labelnames, nil)
+	poolInfo = prometheus.NewDesc(prefixPool+poolInfoName, poolInfoDesc, append(labelnames, "easytier", "stgtype", "extent_size"), nil)
+	poolTierCapacity = prometheus.NewDesc(prefixPool+poolTierCapacityName, poolTierCapacityDesc, append(labelnames, "tier"), nil)
+	poolTierAllocated = prometheus.NewDesc(prefixPool+poolTierAllocatedName, poolTierAllocatedDesc, append(labelnames, "tier"), nil)
+	poolAlertThreshold = prometheus.NewDesc(prefixPool+poolAlertThresholdName, poolAlertThresholdDesc, labelnames, nil)
+	poolOverprovisioned = prometheus.NewDesc(prefixPool+poolOverprovisionedName, poolOverprovisionedDesc, labelnames, nil)
+	poolESERealAllocated = prometheus.NewDesc(prefixPool+poolESERealAllocatedName, poolESERealAllocatedDesc, labelnames, nil)
+	poolESEVirtualAllocated = prometheus.NewDesc(prefixPool+poolESEVirtualAllocatedName, poolESEVirtualAllocatedDesc, labelnames, nil)
+	poolRepositoryCapacity = prometheus.NewDesc(prefixPool+poolRepositoryCapacityName, poolRepositoryCapacityDesc, append(labelnames, "type"), nil)
+	poolRepositoryAllocated = prometheus.NewDesc(prefixPool+poolRepositoryAllocatedName, poolRepositoryAllocatedDesc, append(labelnames, "type"), nil)
 }
 
 // poolCollector collects system metrics
@@ -47,15 +85,25 @@ func (*poolCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- availablePoolCapacity
 	ch <- allocatedPoolCapacity
 	ch <- poolCapacityUsedPercent
+	ch <- poolInfo
+	ch <- poolTierCapacity
+	ch <- poolTierAllocated
+	ch <- poolAlertThreshold
+	ch <- poolOverprovisioned
+	ch <- poolESERealAllocated
+	ch <- poolESEVirtualAllocated
+	ch <- poolRepositoryCapacity
+	ch <- poolRepositoryAllocated
 }
 
 //Collect collects metrics from DS8k Restful API
-func (c *poolCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus.Metric) {
+func (c *poolCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
 	log.Debugln("Entering pools collector ...")
 	reqPoolURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/pools"
-	poolsResp, err := dClient.CallDS8kAPI(reqPoolURL)
+	poolsResp, err := dClient.CallDS8kAPI(ctx, reqPoolURL)
 	if err != nil {
 		log.Errorln("Executing '/api/v1/pools' failed: ", err)
+		return err
 	}
 	log.Debugln("Response of '/api/v1/pools': ", poolsResp)
 	// This is a sample output of /api/v1/polls call
@@ -118,6 +166,29 @@ func (c *poolCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus.M
 		ch <- prometheus.MustNewConstMetric(availablePoolCapacity, prometheus.GaugeValue, pool.Get("capavail").Float(), labelvalues...)
 		ch <- prometheus.MustNewConstMetric(allocatedPoolCapacity, prometheus.GaugeValue, pool.Get("capalloc").Float(), labelvalues...)
 		ch <- prometheus.MustNewConstMetric(poolCapacityUsedPercent, prometheus.GaugeValue, pool.Get("capalloc").Float()/pool.Get("cap").Float(), labelvalues...)
+		ch <- prometheus.MustNewConstMetric(poolInfo, prometheus.GaugeValue, 1, append(labelvalues, pool.Get("easytier").String(), pool.Get("stgtype").String(), pool.Get("extent_size").String())...)
+		for _, tier := range pool.Get("tieralloc").Array() {
+			tierLabelvalues := append(append([]string{}, labelvalues...), tier.Get("tier").String())
+			ch <- prometheus.MustNewConstMetric(poolTierCapacity, prometheus.GaugeValue, tier.Get("cap").Float(), tierLabelvalues...)
+			ch <- prometheus.MustNewConstMetric(poolTierAllocated, prometheus.GaugeValue, tier.Get("allocated").Float(), tierLabelvalues...)
+		}
+		if threshold := pool.Get("threshold"); threshold.Exists() && threshold.String() != "" {
+			ch <- prometheus.MustNewConstMetric(poolAlertThreshold, prometheus.GaugeValue, threshold.Float()/100, labelvalues...)
+		}
+		ch <- prometheus.MustNewConstMetric(poolOverprovisioned, prometheus.GaugeValue, pool.Get("overprovisioned").Float(), labelvalues...)
+		ch <- prometheus.MustNewConstMetric(poolESERealAllocated, prometheus.GaugeValue, pool.Get("real_capacity_allocated_on_ese").Float(), labelvalues...)
+		ch <- prometheus.MustNewConstMetric(poolESEVirtualAllocated, prometheus.GaugeValue, pool.Get("virtual_capacity_allocated_on_ese").Float(), labelvalues...)
+		// The repositories are empty objects if the pool has none.
+		for _, repType := range []string{"ese", "tse"} {
+			repository := pool.Get(repType + "rep")
+			if !repository.Get("cap").Exists() {
+				continue
+			}
+			repLabelvalues := append(append([]string{}, labelvalues...), repType)
+			ch <- prometheus.MustNewConstMetric(poolRepositoryCapacity, prometheus.GaugeValue, repository.Get("cap").Float(), repLabelvalues...)
+			ch <- prometheus.MustNewConstMetric(poolRepositoryAllocated, prometheus.GaugeValue, repository.Get("allocated").Float(), repLabelvalues...)
+		}
 	}
 	log.Debugln("Leaving pools collector.")
+	return nil
 }
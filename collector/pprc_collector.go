@@ -0,0 +1,241 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixPPRC              = "ds8k_pprc_"
+	pprcStateName           = "state"
+	pprcOutOfSyncTracksName = "out_of_sync_tracks"
+	pprcPathStateName       = "path_state"
+	globalMirrorCGAgeName   = "ds8k_globalmirror_consistency_group_age_seconds"
+	pprcStateDesc           = "The state of the PPRC pair, 1 for the current state and 0 for all others."
+	pprcOutOfSyncTracksDesc = "The number of tracks of the PPRC pair that are not yet copied to the secondary volume."
+	pprcPathStateDesc       = "The state of the PPRC path between two I/O ports, 1 for the current state and 0 for all others."
+	globalMirrorCGAgeDesc   = "The age in seconds of the last consistency group formed by the Global Mirror session."
+)
+
+var (
+	pprcState           *prometheus.Desc
+	pprcOutOfSyncTracks *prometheus.Desc
+	pprcPathState       *prometheus.Desc
+	globalMirrorCGAge   *prometheus.Desc
+	pprcStates          = []string{"full_duplex", "copy_pending", "suspended", "target_full_duplex", "target_copy_pending", "target_suspended"}
+	pprcPathStates      = []string{"success", "failed"}
+)
+
+func init() {
+	registerCollector("pprc", defaultDisabled, NewPPRCCollector)
+	labelnames := []string{"target", "target_system", "source_volume", "target_volume"}
+	pprcState = prometheus.NewDesc(prefixPPRC+pprcStateName, pprcStateDesc, append(labelnames, "type", "state"), nil)
+	pprcOutOfSyncTracks = prometheus.NewDesc(prefixPPRC+pprcOutOfSyncTracksName, pprcOutOfSyncTracksDesc, labelnames, nil)
+	pprcPathState = prometheus.NewDesc(prefixPPRC+pprcPathStateName, pprcPathStateDesc, []string{"target", "target_system", "source_lss", "target_lss", "source_port", "target_port", "state"}, nil)
+	globalMirrorCGAge = prometheus.NewDesc(globalMirrorCGAgeName, globalMirrorCGAgeDesc, []string{"target", "session"}, nil)
+}
+
+// pprcCollector collects PPRC (Metro Mirror, Global Copy and Global Mirror) metrics
+type pprcCollector struct {
+}
+
+func NewPPRCCollector() (Collector, error) {
+	return &pprcCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*pprcCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pprcState
+	ch <- pprcOutOfSyncTracks
+	ch <- pprcPathState
+	ch <- globalMirrorCGAge
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *pprcCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering pprc collector ...")
+	var lastErr error
+	reqPPRCURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/cs/pprcs"
+	pprcsResp, err := dClient.CallDS8kAPI(ctx, reqPPRCURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/cs/pprcs' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/cs/pprcs': ", pprcsResp)
+	// This is a sample output of /api/v1/cs/pprcs call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"pprcs": [
+	// 			{
+	// 				"id": "2107-75DXA41_0002:2107-75FAB31_0002",
+	// 				"link": {
+	// 					"href": "https:/10.23.1.10:8452/api/v1/cs/pprcs/2107-75DXA41_0002:2107-75FAB31_0002",
+	// 					"rel": "self"
+	// 				},
+	// 				"out_of_sync_tracks": "0",
+	// 				"state": "full_duplex",
+	// 				"type": "metromirror",
+	// 				"volume_pairs": [
+	// 					{
+	// 						"source_volume": {
+	// 							"id": "0002"
+	// 						},
+	// 						"target_volume": {
+	// 							"id": "0002"
+	// 						}
+	// 					}
+	// 				]
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	pprcsData := gjson.Get(pprcsResp, "data").String()
+	pprcs := gjson.Get(pprcsData, "pprcs").Array()
+	for _, pprc := range pprcs {
+		// With Multi-Target PPRC a volume is paired with more than one
+		// secondary, the remote system tells the pairs apart.
+		targetSystem := pprcTargetSystem(pprc.Get("id").String())
+		for _, pair := range pprc.Get("volume_pairs").Array() {
+			labelvalues := []string{dClient.IpAddress, targetSystem, pair.Get("source_volume.id").String(), pair.Get("target_volume.id").String()}
+			for _, m := range newStateMetrics(pprcState, pprc.Get("state").String(), pprcStates, append(labelvalues, pprc.Get("type").String())...) {
+				ch <- m
+			}
+			ch <- prometheus.MustNewConstMetric(pprcOutOfSyncTracks, prometheus.GaugeValue, pprc.Get("out_of_sync_tracks").Float(), labelvalues...)
+		}
+	}
+
+	reqPathURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/cs/pprcs/paths"
+	pathsResp, err := dClient.CallDS8kAPI(ctx, reqPathURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/cs/pprcs/paths' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/cs/pprcs/paths': ", pathsResp)
+	// This is a sample output of /api/v1/cs/pprcs/paths call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"paths": [
+	// 			{
+	// 				"id": "2107-75DXA41_00:2107-75FAB31_00",
+	// 				"port_pairs": [
+	// 					{
+	// 						"source_port": {
+	// 							"id": "I0000"
+	// 						},
+	// 						"state": "success",
+	// 						"target_port": {
+	// 							"id": "I0100"
+	// 						}
+	// 					}
+	// 				],
+	// 				"source_lss_id": "00",
+	// 				"target_lss_id": "00",
+	// 				"target_system_wwnn": "5005076306FFD6B1"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	pathsData := gjson.Get(pathsResp, "data").String()
+	paths := gjson.Get(pathsData, "paths").Array()
+	for _, path := range paths {
+		// Labelled with the remote system like the pairs, so a suspended pair
+		// can be matched with its failed paths.
+		targetSystem := pprcTargetSystem(path.Get("id").String())
+		for _, portPair := range path.Get("port_pairs").Array() {
+			labelvalues := []string{dClient.IpAddress, targetSystem, path.Get("source_lss_id").String(), path.Get("target_lss_id").String(), portPair.Get("source_port.id").String(), portPair.Get("target_port.id").String()}
+			for _, m := range newStateMetrics(pprcPathState, portPair.Get("state").String(), pprcPathStates, labelvalues...) {
+				ch <- m
+			}
+		}
+	}
+
+	reqGlobalMirrorURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/cs/globalmirrors"
+	globalMirrorsResp, err := dClient.CallDS8kAPI(ctx, reqGlobalMirrorURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/cs/globalmirrors' request failed: ", err)
+		lastErr = err
+	}
+	log.Debugln("Response of '/api/v1/cs/globalmirrors': ", globalMirrorsResp)
+	// This is a sample output of /api/v1/cs/globalmirrors call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"globalmirrors": [
+	// 			{
+	// 				"cg_time": "2019-05-20T01:44:39-0400",
+	// 				"current_time": "2019-05-20T01:44:42-0400",
+	// 				"id": "01",
+	// 				"state": "running"
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	globalMirrorsData := gjson.Get(globalMirrorsResp, "data").String()
+	globalMirrors := gjson.Get(globalMirrorsData, "globalmirrors").Array()
+	for _, globalMirror := range globalMirrors {
+		// Both times come from the device clock, so the age is not affected by clock skew.
+		currentTime, err := time.Parse(ds8kTimeLayout, globalMirror.Get("current_time").String())
+		if err != nil {
+			log.Errorln("Parsing Global Mirror current time failed: ", err)
+			continue
+		}
+		cgTime, err := time.Parse(ds8kTimeLayout, globalMirror.Get("cg_time").String())
+		if err != nil {
+			log.Errorln("Parsing Global Mirror consistency group time failed: ", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(globalMirrorCGAge, prometheus.GaugeValue, currentTime.Sub(cgTime).Seconds(), dClient.IpAddress, globalMirror.Get("id").String())
+	}
+	log.Debugln("Leaving pprc collector.")
+	return lastErr
+}
+
+// pprcTargetSystem returns the remote system of a PPRC pair or path from its
+// ID, for example 2107-75FAB31 from 2107-75DXA41_0002:2107-75FAB31_0002 or
+// 2107-75DXA41_00:2107-75FAB31_00.
+func pprcTargetSystem(id string) string {
+	i := strings.Index(id, ":")
+	if i < 0 {
+		return ""
+	}
+	target := id[i+1:]
+	if j := strings.LastIndex(target, "_"); j >= 0 {
+		target = target[:j]
+	}
+	return target
+}
@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+)
+
+const (
+	prefixSafeguarded            = "ds8k_safeguarded_"
+	safeguardedMultiplierName    = "capacity_multiplier"
+	safeguardedCapacityName      = "capacity_bytes"
+	safeguardedBackupsName       = "backups"
+	safeguardedLastBackupName    = "last_backup_timestamp_seconds"
+	safeguardedRecoveryStateName = "recovery_volume_state"
+	safeguardedMultiplierDesc    = "The safeguarded capacity multiplier of the source volume."
+	safeguardedCapacityDesc      = "The capacity allocated for the safeguarded backups of the source volume in bytes."
+	safeguardedBackupsDesc       = "The number of safeguarded backups retained for the source volume."
+	safeguardedLastBackupDesc    = "Unix timestamp of the most recent safeguarded backup of the source volume."
+	safeguardedRecoveryStateDesc = "The state of the recovery volume of the source volume, 1 for the current state and 0 for all others."
+)
+
+var (
+	safeguardedMultiplier     *prometheus.Desc
+	safeguardedCapacity       *prometheus.Desc
+	safeguardedBackups        *prometheus.Desc
+	safeguardedLastBackup     *prometheus.Desc
+	safeguardedRecoveryState  *prometheus.Desc
+	safeguardedRecoveryStates = []string{"recovering", "recovered", "failed"}
+)
+
+func init() {
+	registerCollector("safeguarded", defaultDisabled, NewSafeguardedCollector)
+	labelnames := []string{"target", "volume"}
+	safeguardedMultiplier = prometheus.NewDesc(prefixSafeguarded+safeguardedMultiplierName, safeguardedMultiplierDesc, labelnames, nil)
+	safeguardedCapacity = prometheus.NewDesc(prefixSafeguarded+safeguardedCapacityName, safeguardedCapacityDesc, append(labelnames, "pool"), nil)
+	safeguardedBackups = prometheus.NewDesc(prefixSafeguarded+safeguardedBackupsName, safeguardedBackupsDesc, labelnames, nil)
+	safeguardedLastBackup = prometheus.NewDesc(prefixSafeguarded+safeguardedLastBackupName, safeguardedLastBackupDesc, labelnames, nil)
+	safeguardedRecoveryState = prometheus.NewDesc(prefixSafeguarded+safeguardedRecoveryStateName, safeguardedRecoveryStateDesc, append(labelnames, "recovery_volume", "state"), nil)
+}
+
+// safeguardedCollector collects Safeguarded Copy metrics
+type safeguardedCollector struct {
+}
+
+func NewSafeguardedCollector() (Collector, error) {
+	return &safeguardedCollector{}, nil
+}
+
+//Describe describes the metrics
+func (*safeguardedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- safeguardedMultiplier
+	ch <- safeguardedCapacity
+	ch <- safeguardedBackups
+	ch <- safeguardedLastBackup
+	ch <- safeguardedRecoveryState
+}
+
+//Collect collects metrics from DS8k Restful API
+func (c *safeguardedCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
+	log.Debugln("Entering safeguarded collector ...")
+	var lastErr error
+	// The pools are only listed to label the capacity with the pool name.
+	reqPoolURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/pools"
+	poolsResp, err := dClient.CallDS8kAPI(ctx, reqPoolURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/pools' request failed: ", err)
+		lastErr = err
+	}
+	poolNames := make(map[string]string)
+	poolsData := gjson.Get(poolsResp, "data").String()
+	for _, pool := range gjson.Get(poolsData, "pools").Array() {
+		poolNames[pool.Get("id").String()] = pool.Get("name").String() + "_" + pool.Get("id").String()
+	}
+
+	reqSafeguardedURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/cs/safeguardedcopies"
+	safeguardedResp, err := dClient.CallDS8kAPI(ctx, reqSafeguardedURL)
+	if err != nil {
+		log.Errorln("Executing '/api/v1/cs/safeguardedcopies' request failed: ", err)
+		return err
+	}
+	log.Debugln("Response of '/api/v1/cs/safeguardedcopies': ", safeguardedResp)
+	// This is a sample output of /api/v1/cs/safeguardedcopies call
+	// {
+	// 	"counts": {
+	// 		"data_counts": 1,
+	// 		"total_counts": 1
+	// 	},
+	// 	"data": {
+	// 		"safeguardedcopies": [
+	// 			{
+	// 				"backups": "12",
+	// 				"capacity": "107374182400",
+	// 				"multiplier": "1.5",
+	// 				"pool": {
+	// 					"id": "P0"
+	// 				},
+	// 				"recent_backup_time": "2019-05-20T01:00:00-0400",
+	// 				"recovery_volume": {
+	// 					"id": "0100",
+	// 					"state": "recovered"
+	// 				},
+	// 				"sourcevolume": {
+	// 					"id": "0002"
+	// 				}
+	// 			}
+	// 		]
+	// 	},
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	}
+	// }
+
+	safeguardedData := gjson.Get(safeguardedResp, "data").String()
+	safeguardedCopies := gjson.Get(safeguardedData, "safeguardedcopies").Array()
+	volumeNames, err := c.volumeNames(ctx, dClient, safeguardedCopies)
+	if err != nil {
+		lastErr = err
+	}
+	for _, safeguardedCopy := range safeguardedCopies {
+		// The volume is labelled name_id like in the volume metrics, a copy
+		// whose volume name is unknown is skipped rather than labelled
+		// differently.
+		volumeName, ok := volumeNames[safeguardedCopy.Get("sourcevolume.id").String()]
+		if !ok {
+			log.Debugf("Skipping safeguarded copy of unknown volume %s of %s", safeguardedCopy.Get("sourcevolume.id").String(), dClient.IpAddress)
+			continue
+		}
+		labelvalues := []string{dClient.IpAddress, volumeName}
+		ch <- prometheus.MustNewConstMetric(safeguardedMultiplier, prometheus.GaugeValue, safeguardedCopy.Get("multiplier").Float(), labelvalues...)
+		if poolName, ok := poolNames[safeguardedCopy.Get("pool.id").String()]; ok {
+			ch <- prometheus.MustNewConstMetric(safeguardedCapacity, prometheus.GaugeValue, safeguardedCopy.Get("capacity").Float(), append(labelvalues, poolName)...)
+		}
+		ch <- prometheus.MustNewConstMetric(safeguardedBackups, prometheus.GaugeValue, safeguardedCopy.Get("backups").Float(), labelvalues...)
+		if recentBackupTime := safeguardedCopy.Get("recent_backup_time").String(); recentBackupTime != "" {
+			recentBackup, err := time.Parse(ds8kTimeLayout, recentBackupTime)
+			if err != nil {
+				log.Errorln("Parsing safeguarded backup time failed: ", err)
+			} else {
+				ch <- prometheus.MustNewConstMetric(safeguardedLastBackup, prometheus.GaugeValue, float64(recentBackup.Unix()), labelvalues...)
+			}
+		}
+		// Only volumes that were recovered have a recovery volume.
+		if recoveryVolume := safeguardedCopy.Get("recovery_volume.id").String(); recoveryVolume != "" {
+			for _, m := range newStateMetrics(safeguardedRecoveryState, safeguardedCopy.Get("recovery_volume.state").String(), safeguardedRecoveryStates, append(labelvalues, recoveryVolume)...) {
+				ch <- m
+			}
+		}
+	}
+	log.Debugln("Leaving safeguarded collector.")
+	return lastErr
+}
+
+// volumeNames returns the name_id of the source volumes of the safeguarded
+// copies, keyed by the volume ID. The backup capacity is allocated in the
+// pool of the source volume, so only the volumes of those pools are listed.
+func (c *safeguardedCollector) volumeNames(ctx context.Context, dClient utils.DS8kClient, safeguardedCopies []gjson.Result) (map[string]string, error) {
+	var lastErr error
+	volumeNames := make(map[string]string)
+	listed := make(map[string]bool)
+	for _, safeguardedCopy := range safeguardedCopies {
+		poolID := safeguardedCopy.Get("pool.id").String()
+		if poolID == "" || listed[poolID] {
+			continue
+		}
+		listed[poolID] = true
+		reqVolumeURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/pools/" + poolID + "/volumes"
+		volumesResp, err := dClient.CallDS8kAPI(ctx, reqVolumeURL)
+		if err != nil {
+			log.Errorln("Executing '/api/v1/pools/"+poolID+"/volumes' request failed: ", err)
+			lastErr = err
+			continue
+		}
+		volumesData := gjson.Get(volumesResp, "data").String()
+		for _, volume := range gjson.Get(volumesData, "volumes").Array() {
+			volumeNames[volume.Get("id").String()] = volume.Get("name").String() + "_" + volume.Get("id").String()
+		}
+	}
+	return volumeNames, lastErr
+}
@@ -1,6 +1,8 @@
 package collector
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/tidwall/gjson"
@@ -19,6 +21,10 @@ const (
 	allocatedSystemCapacityDesc   = "The allocated capacity of system"
 	systemCapacityUsedPercentDesc = "The system capacity utilization."
 	rawSystemCapacityDesc         = "The raw capacity of system"
+	systemInfoName                = "info"
+	systemStateName               = "state"
+	systemInfoDesc                = "Information about the system, value is always 1."
+	systemStateDesc               = "The state of the system, 1 for the current state and 0 for all others."
 )
 
 var (
@@ -27,6 +33,9 @@ var (
 	allocatedSystemCapacity   *prometheus.Desc
 	systemCapacityUsedPercent *prometheus.Desc
 	rawSystemCapacity         *prometheus.Desc
+	systemInfo                *prometheus.Desc
+	systemState               *prometheus.Desc
+	systemStates              = []string{"online", "offline", "fenced"}
 )
 
 func init() {
@@ -37,6 +46,8 @@ func init() {
 	allocatedSystemCapacity = prometheus.NewDesc(prefixSys+allocatedSystemCapacityName, allocatedSystemCapacityDesc, labelnames, nil)
 	systemCapacityUsedPercent = prometheus.NewDesc(prefixSys+systemCapacityUsedPercentName, systemCapacityUsedPercentDesc, labelnames, nil)
 	rawSystemCapacity = prometheus.NewDesc(prefixSys+rawSystemCapacityName, rawSystemCapacityDesc, labelnames, nil)
+	systemInfo = prometheus.NewDesc(prefixSys+systemInfoName, systemInfoDesc, append(labelnames, "mtm", "bundle", "release", "sn", "wwnn"), nil)
+	systemState = prometheus.NewDesc(prefixSys+systemStateName, systemStateDesc, append(labelnames, "state"), nil)
 }
 
 // poolCollector collects system metrics
@@ -54,15 +65,18 @@ func (*systemCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- allocatedSystemCapacity
 	ch <- systemCapacityUsedPercent
 	ch <- rawSystemCapacity
+	ch <- systemInfo
+	ch <- systemState
 }
 
 //Collect collects metrics from DS8k Restful API
-func (c *systemCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus.Metric) {
+func (c *systemCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
 	log.Debugln("Entering systems collector ...")
 	reqSystemURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/systems"
-	systemsResp, err := dClient.CallDS8kAPI(reqSystemURL)
+	systemsResp, err := dClient.CallDS8kAPI(ctx, reqSystemURL)
 	if err != nil {
 		log.Errorln("Executing '/api/v1/systems' failed: ", err)
+		return err
 	}
 	log.Debugln("Response of '/api/v1/systems': ", systemsResp)
 	// This is the sample output of /api/v1/systems
@@ -105,6 +119,11 @@ func (c *systemCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus
 		ch <- prometheus.MustNewConstMetric(allocatedSystemCapacity, prometheus.GaugeValue, system.Get("capalloc").Float(), labelvalues...)
 		ch <- prometheus.MustNewConstMetric(systemCapacityUsedPercent, prometheus.GaugeValue, system.Get("capalloc").Float()/system.Get("cap").Float(), labelvalues...)
 		ch <- prometheus.MustNewConstMetric(rawSystemCapacity, prometheus.GaugeValue, system.Get("capraw").Float(), labelvalues...)
+		ch <- prometheus.MustNewConstMetric(systemInfo, prometheus.GaugeValue, 1, append(labelvalues, system.Get("MTM").String(), system.Get("bundle").String(), system.Get("release").String(), system.Get("sn").String(), system.Get("wwnn").String())...)
+		for _, m := range newStateMetrics(systemState, system.Get("state").String(), systemStates, labelvalues...) {
+			ch <- m
+		}
 	}
 	log.Debugln("Leaving systems collector.")
+	return nil
 }
@@ -0,0 +1,169 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tidwall/gjson"
+	"github.ibm.com/ZaaS/ds8k-exporter/utils"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	performanceVolumes       = kingpin.Flag("collector.performance.volumes", "Collect the performance of volumes, which needs one request per selected volume.").Default("false").Bool()
+	performanceVolumeInclude = kingpin.Flag("collector.performance.volumes.include", "Regular expression matched against the ID, name or name_id of a volume to select it for performance collection.").String()
+	performanceVolumePools   = kingpin.Flag("collector.performance.volumes.pool", "Select only the volumes of this pool for performance collection, by ID, name or name_id. Can be repeated.").Strings()
+	performanceVolumeTop     = kingpin.Flag("collector.performance.volumes.top", "Expose only the N selected volumes with the most IOPS in their newest sample, 0 exposes all.").Default("0").Int()
+
+	volumePerformance *ioPerformance
+	// volumeSelection is the selector compiled from the flags by ValidateFlags.
+	volumeSelection *volumeSelector
+)
+
+func init() {
+	volumePerformance = newIOPerformance(prefixPerformance+"volume_", "volume", []string{"target", "volume", "pool"})
+}
+
+// volumeSelector selects the volumes whose performance is collected.
+type volumeSelector struct {
+	include *regexp.Regexp
+	pools   map[string]bool
+	top     int
+}
+
+// ValidateFlags compiles the collector flags that kingpin can't check, so an
+// invalid value stops the exporter at startup instead of failing every scrape.
+func ValidateFlags() error {
+	s, err := newVolumeSelector()
+	if err != nil {
+		return fmt.Errorf("invalid --collector.performance.volumes.include: %s", err)
+	}
+	volumeSelection = s
+	return nil
+}
+
+func newVolumeSelector() (*volumeSelector, error) {
+	s := &volumeSelector{top: *performanceVolumeTop}
+	if *performanceVolumeInclude != "" {
+		// Compile the pattern on its own first, so errors refer to it and not to the anchored pattern.
+		if _, err := regexp.Compile(*performanceVolumeInclude); err != nil {
+			return nil, err
+		}
+		s.include = regexp.MustCompile("^(?:" + *performanceVolumeInclude + ")$")
+	}
+	if len(*performanceVolumePools) > 0 {
+		s.pools = make(map[string]bool)
+		for _, pool := range *performanceVolumePools {
+			s.pools[pool] = true
+		}
+	}
+	return s, nil
+}
+
+// selectPool reports whether the volumes of the pool are selected.
+func (s *volumeSelector) selectPool(pool gjson.Result) bool {
+	if s.pools == nil {
+		return true
+	}
+	id := pool.Get("id").String()
+	name := pool.Get("name").String()
+	return s.pools[id] || s.pools[name] || s.pools[name+"_"+id]
+}
+
+// selectVolume reports whether the volume is selected.
+func (s *volumeSelector) selectVolume(volume gjson.Result) bool {
+	if s.include == nil {
+		return true
+	}
+	id := volume.Get("id").String()
+	name := volume.Get("name").String()
+	return s.include.MatchString(id) || s.include.MatchString(name) || s.include.MatchString(name+"_"+id)
+}
+
+// volumeSamples are the performance samples of one volume.
+type volumeSamples struct {
+	key         string
+	labelvalues []string
+	samples     []gjson.Result
+	iops        float64
+}
+
+// collectVolumePerformance collects the performance of the selected volumes
+// of the pools. With a top N only the N volumes with the most IOPS are exposed.
+func (c *performanceCollector) collectVolumePerformance(ctx context.Context, dClient utils.DS8kClient, pools []gjson.Result, ch chan<- prometheus.Metric) error {
+	var lastErr error
+	var selected []volumeSamples
+	for _, pool := range pools {
+		if !c.volumes.selectPool(pool) {
+			continue
+		}
+		poolID := pool.Get("id").String()
+		poolName := pool.Get("name").String() + "_" + poolID
+		reqVolumeURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/pools/" + poolID + "/volumes"
+		volumesResp, err := dClient.CallDS8kAPI(ctx, reqVolumeURL)
+		if err != nil {
+			log.Errorln("Executing '/api/v1/pools/"+poolID+"/volumes' request failed: ", err)
+			lastErr = err
+			continue
+		}
+		volumesData := gjson.Get(volumesResp, "data").String()
+		volumes := gjson.Get(volumesData, "volumes").Array()
+		for _, volume := range volumes {
+			if !c.volumes.selectVolume(volume) {
+				continue
+			}
+			volumeID := volume.Get("id").String()
+			key, samples, err := fetchPerformance(ctx, dClient, "/api/v1/volumes/"+volumeID)
+			if err != nil {
+				lastErr = err
+			}
+			if len(samples) == 0 {
+				continue
+			}
+			selected = append(selected, volumeSamples{
+				key:         key,
+				labelvalues: []string{dClient.IpAddress, volume.Get("name").String() + "_" + volumeID, poolName},
+				samples:     samples,
+				iops:        newestPerformanceSample(samples).Get("IOPS.total").Float(),
+			})
+		}
+	}
+
+	if c.volumes.top > 0 && len(selected) > c.volumes.top {
+		sort.SliceStable(selected, func(i, j int) bool { return selected[i].iops > selected[j].iops })
+		for _, v := range selected[c.volumes.top:] {
+			// The volume is not exposed, so its next window must not reach back to its last exposed sample.
+			lastSampleTimes.Delete(v.key)
+		}
+		selected = selected[:c.volumes.top]
+	}
+	for _, v := range selected {
+		labelvalues := v.labelvalues
+		emitPerformanceSamples(dClient, v.key, v.samples, ch, func(sample gjson.Result) []performanceValue {
+			return volumePerformance.values(sample, labelvalues)
+		})
+	}
+	return lastErr
+}
+
+// newestPerformanceSample returns the sample with the latest sample time.
+func newestPerformanceSample(samples []gjson.Result) gjson.Result {
+	var newest gjson.Result
+	var newestTime time.Time
+	for _, sample := range samples {
+		sampleTime, err := time.Parse(ds8kTimeLayout, sample.Get("performancesampletime").String())
+		if err != nil {
+			continue
+		}
+		if newestTime.IsZero() || sampleTime.After(newestTime) {
+			newest = sample
+			newestTime = sampleTime
+		}
+	}
+	return newest
+}
@@ -1,6 +1,8 @@
 package collector
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/tidwall/gjson"
@@ -15,12 +17,28 @@ const (
 	totalVolumeCapacityDesc       = "The total capacity of volume."
 	allocatedVolumeCapacityDesc   = "The allocated capacity of volume."
 	volumeCapacityUsedPercentDesc = "The volume capacity utilization."
+	volumeRealCapacityName        = "real_capacity_bytes"
+	volumeVirtualCapacityName     = "virtual_capacity_bytes"
+	volumeThinProvisioningName    = "thin_provisioning_info"
+	volumeRealCapacityDesc        = "The real capacity of the volume in bytes."
+	volumeVirtualCapacityDesc     = "The virtual capacity of the space efficient volume in bytes."
+	volumeThinProvisioningDesc    = "The thin provisioning type of the volume (none, ese or tse), value is always 1."
+	volumeInfoName                = "info"
+	volumeStateName               = "state"
+	volumeInfoDesc                = "Information about the volume, value is always 1."
+	volumeStateDesc               = "The state of the volume, 1 for the current state and 0 for all others."
 )
 
 var (
 	totalVolumeCapacity       *prometheus.Desc
 	allocatedVolumeCapacity   *prometheus.Desc
 	volumeCapacityUsedPercent *prometheus.Desc
+	volumeRealCapacity        *prometheus.Desc
+	volumeVirtualCapacity     *prometheus.Desc
+	volumeThinProvisioning    *prometheus.Desc
+	volumeInfo                *prometheus.Desc
+	volumeState               *prometheus.Desc
+	volumeStates              = []string{"normal", "read_only", "inaccessible", "virtual_space_fault", "indeterminate"}
 )
 
 func init() {
@@ -29,6 +47,11 @@ func init() {
 	totalVolumeCapacity = prometheus.NewDesc(prefixVolume+totalVolumeCapacityName, totalVolumeCapacityDesc, labelnames, nil)
 	allocatedVolumeCapacity = prometheus.NewDesc(prefixVolume+allocatedVolumeCapacityName, allocatedVolumeCapacityDesc, labelnames, nil)
 	volumeCapacityUsedPercent = prometheus.NewDesc(prefixVolume+volumeCapacityUsedPercentName, volumeCapacityUsedPercentDesc, labelnames, nil)
+	volumeRealCapacity = prometheus.NewDesc(prefixVolume+volumeRealCapacityName, volumeRealCapacityDesc, labelnames, nil)
+	volumeVirtualCapacity = prometheus.NewDesc(prefixVolume+volumeVirtualCapacityName, volumeVirtualCapacityDesc, labelnames, nil)
+	volumeThinProvisioning = prometheus.NewDesc(prefixVolume+volumeThinProvisioningName, volumeThinProvisioningDesc, append(labelnames, "type"), nil)
+	volumeInfo = prometheus.NewDesc(prefixVolume+volumeInfoName, volumeInfoDesc, append(labelnames, "datatype", "allocmethod", "lss", "mtm"), nil)
+	volumeState = prometheus.NewDesc(prefixVolume+volumeStateName, volumeStateDesc, append(labelnames, "state"), nil)
 }
 
 // poolCollector collects system metrics
@@ -44,15 +67,21 @@ func (*volumeCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- totalVolumeCapacity
 	ch <- allocatedVolumeCapacity
 	ch <- volumeCapacityUsedPercent
+	ch <- volumeRealCapacity
+	ch <- volumeVirtualCapacity
+	ch <- volumeThinProvisioning
+	ch <- volumeInfo
+	ch <- volumeState
 }
 
 //Collect collects metrics from DS8k Restful API
-func (c *volumeCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus.Metric) {
+func (c *volumeCollector) Collect(ctx context.Context, dClient utils.DS8kClient, ch chan<- prometheus.Metric) error {
 	log.Debugln("Entering volumes collector ...")
 	reqPoolURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + "/api/v1/pools"
-	poolsResp, err := dClient.CallDS8kAPI(reqPoolURL)
+	poolsResp, err := dClient.CallDS8kAPI(ctx, reqPoolURL)
 	if err != nil {
 		log.Errorln("Executing '/api/v1/pools' request failed: ", err)
+		return err
 	}
 	log.Debugln("Response of '/api/v1/pools': ", poolsResp)
 	// This is a sample output of /api/v1/polls call
@@ -110,14 +139,17 @@ func (c *volumeCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus
 	poolsData := gjson.Get(poolsResp, "data").String()
 	pools := gjson.Get(poolsData, "pools").Array()
 	var poolIds []string
+	var lastErr error
 	for _, pool := range pools {
 		poolIds = append(poolIds, pool.Get("id").String())
 		poolID := pool.Get("id").String()
 		requestVolume := "/api/v1/pools/" + poolID + "/volumes"
 		reqVolumeURL := "https://" + dClient.IpAddress + ":" + ds8KAPIPort + requestVolume
-		VolumesResp, err := dClient.CallDS8kAPI(reqVolumeURL)
+		VolumesResp, err := dClient.CallDS8kAPI(ctx, reqVolumeURL)
 		if err != nil {
-			log.Errorln("Executing '/api/v1/pools/"+poolID+"/volumes' request failed: %s", err)
+			log.Errorln("Executing '/api/v1/pools/"+poolID+"/volumes' request failed: ", err)
+			lastErr = err
+			continue
 		}
 		log.Debugln("result of '/api/v1/pools/"+poolID+"/volumes': ", poolsResp)
 		// This is the sample output of /api/v1/pools/poolID/volumes
@@ -184,8 +216,16 @@ func (c *volumeCollector) Collect(dClient utils.DS8kClient, ch chan<- prometheus
 			ch <- prometheus.MustNewConstMetric(totalVolumeCapacity, prometheus.GaugeValue, volume.Get("cap").Float(), labelvalues...)
 			ch <- prometheus.MustNewConstMetric(allocatedVolumeCapacity, prometheus.GaugeValue, volume.Get("capalloc").Float(), labelvalues...)
 			ch <- prometheus.MustNewConstMetric(volumeCapacityUsedPercent, prometheus.GaugeValue, volume.Get("capalloc").Float()/volume.Get("cap").Float(), labelvalues...)
+			ch <- prometheus.MustNewConstMetric(volumeRealCapacity, prometheus.GaugeValue, volume.Get("real_cap").Float(), labelvalues...)
+			ch <- prometheus.MustNewConstMetric(volumeVirtualCapacity, prometheus.GaugeValue, volume.Get("virtual_cap").Float(), labelvalues...)
+			ch <- prometheus.MustNewConstMetric(volumeThinProvisioning, prometheus.GaugeValue, 1, append(labelvalues, volume.Get("tp").String())...)
+			ch <- prometheus.MustNewConstMetric(volumeInfo, prometheus.GaugeValue, 1, append(labelvalues, volume.Get("datatype").String(), volume.Get("allocmethod").String(), volume.Get("lss.id").String(), volume.Get("MTM").String())...)
+			for _, m := range newStateMetrics(volumeState, volume.Get("state").String(), volumeStates, labelvalues...) {
+				ch <- m
+			}
 		}
 
 	}
 	log.Debugln("Leaving volumes collector.")
+	return lastErr
 }
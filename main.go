@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -23,7 +29,10 @@ var (
 	username               = kingpin.Flag("web.user", "Username to use when connecting to DS8K RESTful API").String()
 	passwd                 = kingpin.Flag("web.passwd", "Passwd to use when connecting to DS8K RESTful API").String()
 	// maxRequests            = kingpin.Flag("web.max-requests", "Maximum number of parallel scrape requests. Use 0 to disable.").Default("40").Int()
-	location        = kingpin.Flag("location", "The location or timezone of the storage device, for example: America/New_York").Default("").String()
+	location        = kingpin.Flag("location", "The default location or timezone of the storage devices, for example: America/New_York. Overridden by the location of a target.").Default("").String()
+	pollingEnabled  = kingpin.Flag("polling.enabled", "Collect targets in the background and serve the last collected metrics instead of collecting on every scrape.").Bool()
+	pollingInterval = kingpin.Flag("polling.interval", "Default interval between two background collections of a target.").Default("60s").Duration()
+	timeoutOffset   = kingpin.Flag("web.timeout-offset", "Offset to subtract from the timeout Prometheus sends in the X-Prometheus-Scrape-Timeout-Seconds header, to leave time to return the results.").Default("0.5s").Duration()
 	cfg             *utils.Config
 	enableCollector bool = true
 )
@@ -32,6 +41,8 @@ type handler struct {
 	// exporterMetricsRegistry is a separate registry for the metrics about the exporter itself.
 	exporterMetricsRegistry *prometheus.Registry
 	includeExporterMetrics  bool
+	// poller serves the cached snapshots in polling mode, it is nil in on-demand mode.
+	poller *collector.Poller
 	// maxRequests             int
 }
 
@@ -42,15 +53,14 @@ func main() {
 	kingpin.Version(version.Print("ds8k_exporter"))
 	kingpin.HelpFlag.Short('h')
 	kingpin.Parse()
+	if err := collector.ValidateFlags(); err != nil {
+		log.Fatalf("Invalid flags: %s", err)
+	}
 
 	//Bail early if the config is bad.
 	log.Infoln("Loading config from", *configFile)
 	var err error
-	if *location == "" {
-		log.Fatalln("Please input the location of ds8k devices.")
-	}
-
-	cfg, err = utils.GetConfig(*configFile)
+	cfg, err = utils.GetConfig(*configFile, *location)
 
 	if err != nil {
 		log.Fatalf("Error parsing config file: %s", err)
@@ -59,9 +69,18 @@ func main() {
 	log.Infoln("Starting ds8k_exporter", version.Info())
 	log.Infoln("Build context", version.BuildContext())
 
+	var poller *collector.Poller
+	if *pollingEnabled {
+		poller, err = collector.NewPoller(cfg.Targets, *pollingInterval)
+		if err != nil {
+			log.Fatalf("Couldn't create poller: %s", err)
+		}
+		poller.Start()
+	}
+
 	//Launch http services
 	// http.HandleFunc(*metricsPath, handlerMetricRequest)
-	http.Handle(*metricsPath, newHandler(!*disableExporterMetrics))
+	http.Handle(*metricsPath, newHandler(!*disableExporterMetrics, poller))
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
@@ -77,6 +96,16 @@ func main() {
 		}
 
 	})
+	// Log out the auth tokens on shutdown, so they don't pile up on the HMC.
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		log.Infoln("Shutting down, logging out auth tokens")
+		utils.Logout()
+		os.Exit(0)
+	}()
+
 	log.Infof("Listening for %s on %s\n", *metricsPath, *listenAddress)
 	log.Fatal(http.ListenAndServe(*listenAddress, nil))
 }
@@ -96,12 +125,14 @@ func targetsForRequest(r *http.Request) ([]utils.Targets, error) {
 	return nil, fmt.Errorf("The target '%s' is not defined in the configuration file", reqTarget)
 }
 
-func newHandler(includeExporterMetrics bool) *handler {
+func newHandler(includeExporterMetrics bool, poller *collector.Poller) *handler {
 	h := &handler{
 		exporterMetricsRegistry: prometheus.NewRegistry(),
 		includeExporterMetrics:  includeExporterMetrics,
+		poller:                  poller,
 		// maxRequests:             maxRequests,
 	}
+	h.exporterMetricsRegistry.MustRegister(utils.RequestDuration)
 	if h.includeExporterMetrics {
 		h.exporterMetricsRegistry.MustRegister(
 			prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
@@ -120,7 +151,13 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), 400)
 			return
 		}
-		handler, err := h.innerHandler(targets...)
+		ctx, cancel, err := scrapeContext(r)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		defer cancel()
+		handler, err := h.innerHandler(ctx, targets...)
 		if err != nil {
 			log.Warnln("Couldn't create  metrics handler:", err)
 			w.WriteHeader(http.StatusBadRequest)
@@ -134,22 +171,49 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (h *handler) innerHandler(targets ...utils.Targets) (http.Handler, error) {
-	registry := prometheus.NewRegistry()
-	dsc, err := collector.NewDS8kCollector(targets, *location) //new a DS8k Collector
+// scrapeContext returns the context of the scrape request. Its deadline is the
+// scrape timeout of Prometheus minus the timeout offset, if Prometheus sent one.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
 	if err != nil {
-		log.Fatalf("Couldn't create collector: %s", err)
+		return nil, nil, fmt.Errorf("Failed to parse timeout from Prometheus header: %s", err)
 	}
-	if enableCollector == true {
-		log.Infof("Enabled collectors:")
-		for n := range dsc.Collectors {
-			log.Infof(" - %s", n)
-		}
-		enableCollector = false
+	timeout := time.Duration(seconds * float64(time.Second))
+	if *timeoutOffset < timeout {
+		timeout -= *timeoutOffset
 	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
+}
+
+func (h *handler) innerHandler(ctx context.Context, targets ...utils.Targets) (http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	if h.poller != nil {
+		// Serve the last snapshots collected in the background.
+		if err := registry.Register(h.poller.Collector(targets...)); err != nil {
+			return nil, fmt.Errorf("couldn't register ds8k collector: %s", err)
+		}
+	} else {
+		dsc, err := collector.NewDS8kCollector(ctx, targets) //new a DS8k Collector
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create collector: %s", err)
+		}
+		if enableCollector == true {
+			log.Infof("Enabled collectors:")
+			for n := range dsc.Collectors {
+				log.Infof(" - %s", n)
+			}
+			enableCollector = false
+		}
 
-	if err := registry.Register(dsc); err != nil {
-		return nil, fmt.Errorf("couldn't register ds8k collector: %s", err)
+		if err := registry.Register(dsc); err != nil {
+			return nil, fmt.Errorf("couldn't register ds8k collector: %s", err)
+		}
 	}
 	handler := promhttp.HandlerFor(
 		prometheus.Gatherers{h.exporterMetricsRegistry, registry},
@@ -2,47 +2,169 @@ package utils
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/tidwall/gjson"
 )
 
+const (
+	defaultTimeout         = 45 * time.Second
+	defaultMaxIdleConns    = 10
+	defaultIdleConnTimeout = 90 * time.Second
+)
+
+var (
+	// httpClients caches one http.Client per target, so connections and TLS
+	// sessions are reused across requests and scrapes.
+	httpClients sync.Map
+
+	// RequestDuration observes the latency of requests to the DS8K RESTful API.
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ds8k_request_duration_seconds",
+		Help:    "Duration of requests to the DS8K RESTful API by endpoint.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20, 45},
+	}, []string{"target", "endpoint"})
+
+	// resourceNames are the path segments of the DS8K RESTful API that name a
+	// resource. All other segments are IDs and are replaced in the endpoint label.
+	resourceNames = map[string]bool{
+		"api": true, "v1": true, "tokens": true, "systems": true, "pools": true, "volumes": true,
+		"performance": true, "ioports": true, "hosts": true, "host_ports": true, "cs": true,
+		"flashcopies": true, "pprcs": true, "paths": true, "globalmirrors": true, "ranks": true, "nodes": true,
+		"frames": true, "enclosures": true, "arrays": true, "drives": true, "lss": true, "events": true, "problems": true,
+		"encryption_groups": true, "key_servers": true, "safeguardedcopies": true,
+	}
+)
+
 type DS8kClient struct {
 	UserName   string
 	Password   string
-	AuthToken  string
 	IpAddress  string
 	ErrorCount float64
 	Location   string
+	// PerformanceWindow and PerformanceInterval select the performance samples, see Targets.
+	PerformanceWindow   time.Duration
+	PerformanceInterval time.Duration
+	httpClient          *http.Client
+	tokens              *tokenManager
 }
 
-func (ds8kClient *DS8kClient) RetriveAuthToken() (authToken string, err error) {
-	reqAuthURL := "https://" + ds8kClient.IpAddress + ":8452/api/v1/tokens"
+// NewDS8kClient creates a DS8kClient for the target, sharing the target's
+// long-lived http.Client with all other clients of the same target.
+func NewDS8kClient(target Targets) (*DS8kClient, error) {
+	httpclient, err := httpClientFor(target)
+	if err != nil {
+		return nil, err
+	}
+	return &DS8kClient{
+		UserName:            target.Userid,
+		Password:            target.Password,
+		IpAddress:           target.IpAddress,
+		Location:            target.Location,
+		PerformanceWindow:   target.PerformanceWindow,
+		PerformanceInterval: target.PerformanceInterval,
+		httpClient:          httpclient,
+		tokens:              tokenManagerFor(target.IpAddress),
+	}, nil
+}
+
+func httpClientFor(target Targets) (*http.Client, error) {
+	if c, ok := httpClients.Load(target.IpAddress); ok {
+		return c.(*http.Client), nil
+	}
+	tlsConfig, err := target.TLS.build()
+	if err != nil {
+		return nil, err
+	}
+	timeout := defaultTimeout
+	if target.Timeout > 0 {
+		timeout = target.Timeout
+	}
+	maxIdleConns := defaultMaxIdleConns
+	if target.MaxIdleConns > 0 {
+		maxIdleConns = target.MaxIdleConns
+	}
+	idleConnTimeout := defaultIdleConnTimeout
+	if target.IdleConnTimeout > 0 {
+		idleConnTimeout = target.IdleConnTimeout
+	}
 	httpclient := &http.Client{Transport: &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
-			DualStack: true,
 		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		IdleConnTimeout:       idleConnTimeout,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
+		TLSClientConfig:       tlsConfig,
 	},
-		Timeout: 45 * time.Second}
+		Timeout: timeout}
+	c, _ := httpClients.LoadOrStore(target.IpAddress, httpclient)
+	return c.(*http.Client), nil
+}
+
+// do sends the request with the target's http.Client and observes its duration.
+func (ds8kClient *DS8kClient) do(req *http.Request) (*http.Response, error) {
+	if ds8kClient.httpClient == nil {
+		return nil, fmt.Errorf("DS8kClient for %s was not created with NewDS8kClient", ds8kClient.IpAddress)
+	}
+	start := time.Now()
+	resp, err := ds8kClient.httpClient.Do(req)
+	received := time.Now()
+	RequestDuration.WithLabelValues(ds8kClient.IpAddress, endpointOf(req.URL)).Observe(received.Sub(start).Seconds())
+	if err != nil {
+		return resp, err
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		certificateExpiry.Store(ds8kClient.IpAddress, resp.TLS.PeerCertificates[0].NotAfter)
+	}
+	observeDate(ds8kClient.IpAddress, resp.Header, start, received)
+	return resp, nil
+}
+
+// endpointOf returns the path of the URL with all IDs replaced by "{id}", for
+// example /api/v1/pools/{id}/volumes.
+func endpointOf(u *url.URL) string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, s := range segments {
+		if !resourceNames[s] {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Authenticate makes sure the client has a valid auth token. cached reports
+// whether an existing token was reused.
+func (ds8kClient *DS8kClient) Authenticate(ctx context.Context) (cached bool, err error) {
+	_, cached, err = ds8kClient.tokens.get(ctx, ds8kClient)
+	return cached, err
+}
+
+// requestToken requests a new auth token together with its expiry time and
+// idle timeout, which are zero if the response does not contain them.
+func (ds8kClient *DS8kClient) requestToken(ctx context.Context) (authToken string, expires time.Time, idleTimeout time.Duration, err error) {
+	reqAuthURL := "https://" + ds8kClient.IpAddress + ":8452/api/v1/tokens"
 
 	postValue := []byte(`{ "request" : { "params" : { "username" : "` + ds8kClient.UserName + `" , "password" : "` + ds8kClient.Password + ` "} } }`)
 	req, _ := http.NewRequest("POST", reqAuthURL, bytes.NewBuffer(postValue))
+	req = req.WithContext(ctx)
 	req.Header.Add("Content-Type", "application/json")
-	resp, err := httpclient.Do(req)
+	resp, err := ds8kClient.do(req)
 	if err != nil {
 		log.Errorf("Error doing http request URL[%s] Error: %v", reqAuthURL, err)
 		err = fmt.Errorf("Error doing http request URL[%s] Error: %v", reqAuthURL, err)
@@ -59,50 +181,82 @@ func (ds8kClient *DS8kClient) RetriveAuthToken() (authToken string, err error) {
 		log.Errorln("Got a non 200 response code: ", resp.StatusCode)
 		log.Debugln("response was: ", resp)
 		ds8kClient.ErrorCount++
-		return "", fmt.Errorf("received non 200 error code: %v. the response was: %v", resp.Status, resp)
+		err = fmt.Errorf("received non 200 error code: %v. the response was: %v", resp.Status, resp)
+		return
 	}
 	respbody, err := ioutil.ReadAll(resp.Body)
 	body := string(respbody)
 	log.Debugf("Response Body: %v", body)
-	tokenInfo := gjson.Get(body, "token").String()
-	authToken = gjson.Get(tokenInfo, "token").String()
+	// This is the sample output of /api/v1/tokens
+	// {
+	// 	"server": {
+	// 		"code": "",
+	// 		"message": "Operation done successfully.",
+	// 		"status": "ok"
+	// 	},
+	// 	"token": {
+	// 		"expired_time": "2019-07-10T04:18:55-0400",
+	// 		"max_idle_interval": "1800000",
+	// 		"token": "a0bf6d4b"
+	// 	}
+	// }
+	tokenInfo := gjson.Get(body, "token")
+	authToken = tokenInfo.Get("token").String()
+	if expiredTime := tokenInfo.Get("expired_time").String(); expiredTime != "" {
+		expires, err = time.Parse(tokenTimeLayout, expiredTime)
+		if err != nil {
+			log.Warnf("Parsing token expiry time %s failed: %v", expiredTime, err)
+			err = nil
+		}
+	}
+	idleTimeout = time.Duration(tokenInfo.Get("max_idle_interval").Int()) * time.Millisecond
 	log.Debugf("AuthToken is: %v", authToken)
-	return authToken, err
+	return authToken, expires, idleTimeout, err
 
 }
 
-func (ds8kClient *DS8kClient) CallDS8kAPI(request string) (body string, err error) {
-	httpclient := &http.Client{Transport: &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			DualStack: true,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-	},
-		Timeout: 45 * time.Second}
+// CallDS8kAPI sends a GET request to the DS8K RESTful API. The request is
+// canceled when ctx is done.
+func (ds8kClient *DS8kClient) CallDS8kAPI(ctx context.Context, request string) (body string, err error) {
+	token, _, err := ds8kClient.tokens.get(ctx, ds8kClient)
+	if err != nil {
+		return "", err
+	}
+	body, statusCode, err := ds8kClient.get(ctx, request, token)
+	if statusCode == http.StatusUnauthorized {
+		// The token was revoked or timed out on the HMC, authenticate once more.
+		ds8kClient.tokens.invalidate(token)
+		token, _, err = ds8kClient.tokens.get(ctx, ds8kClient)
+		if err != nil {
+			return "", err
+		}
+		body, _, err = ds8kClient.get(ctx, request, token)
+	}
+	if err == nil {
+		ds8kClient.tokens.touch(token)
+	}
+	return body, err
+}
 
+// get sends a GET request with the auth token and returns the body and status code.
+func (ds8kClient *DS8kClient) get(ctx context.Context, request string, token string) (body string, statusCode int, err error) {
 	// New POST request
 	req, _ := http.NewRequest("GET", request, nil)
+	req = req.WithContext(ctx)
 	// header parameters
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-Auth-Token", ds8kClient.AuthToken)
-	resp, err := httpclient.Do(req)
+	req.Header.Add("X-Auth-Token", token)
+	resp, err := ds8kClient.do(req)
 	if err != nil {
-		return "", fmt.Errorf("\n Error connecting to : %v. the error was: %v", request, err)
+		return "", 0, fmt.Errorf("\n Error connecting to : %v. the error was: %v", request, err)
 	}
 	defer resp.Body.Close()
 	respbody, err := ioutil.ReadAll(resp.Body)
 	body = string(respbody)
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("\nGot error code: %v when accessing URL: %s\n Body text is: %s", resp.StatusCode, request, respbody)
+		return "", resp.StatusCode, fmt.Errorf("\nGot error code: %v when accessing URL: %s\n Body text is: %s", resp.StatusCode, request, respbody)
 	}
-	return body, nil
+	return body, resp.StatusCode, nil
 
 }
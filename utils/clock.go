@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// deviceClocks caches the clock of each target as seen by the exporter.
+var deviceClocks sync.Map
+
+// deviceClock is the skew between the HMC clock and the exporter clock, and the
+// UTC offset the device reports its timestamps in.
+type deviceClock struct {
+	mu        sync.Mutex
+	skew      time.Duration
+	skewKnown bool
+	zone      *time.Location
+}
+
+func deviceClockFor(ipAddress string) *deviceClock {
+	c, _ := deviceClocks.LoadOrStore(ipAddress, &deviceClock{})
+	return c.(*deviceClock)
+}
+
+// observeDate updates the clock skew of the target from the Date header of a
+// response to a request sent at start and received at received.
+func observeDate(ipAddress string, header http.Header, start time.Time, received time.Time) {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// The header is truncated to the second, so its mean error is half a second.
+	// The device set it at some point during the request, assume the middle.
+	middle := start.Add(received.Sub(start) / 2)
+	c := deviceClockFor(ipAddress)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skew = date.Add(500 * time.Millisecond).Sub(middle)
+	c.skewKnown = true
+}
+
+// ClockSkew returns how far the HMC clock of the target is ahead of the exporter clock.
+func ClockSkew(ipAddress string) (time.Duration, bool) {
+	c := deviceClockFor(ipAddress)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skew, c.skewKnown
+}
+
+// SetDeviceZone records the UTC offset of a timestamp reported by the target,
+// which is used to present times to the device in its own zone.
+func SetDeviceZone(ipAddress string, t time.Time) {
+	name, offset := t.Zone()
+	c := deviceClockFor(ipAddress)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.zone = time.FixedZone(name, offset)
+}
+
+// DeviceTime returns the current time of the device in its zone. Until the
+// device reported a timestamp, the configured location or else UTC is used.
+func (ds8kClient *DS8kClient) DeviceTime() time.Time {
+	c := deviceClockFor(ds8kClient.IpAddress)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	zone := c.zone
+	if zone == nil {
+		zone = time.UTC
+		if location, err := time.LoadLocation(ds8kClient.Location); err == nil && ds8kClient.Location != "" {
+			zone = location
+		}
+	}
+	return time.Now().Add(c.skew).In(zone)
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"io/ioutil"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -14,9 +16,25 @@ type Targets struct {
 	IpAddress string `yaml:"ipAddress"`
 	Userid    string `yaml:"userid"`
 	Password  string `yaml:"password"`
+	// Location is the timezone of the storage device, for example America/New_York.
+	// It is only used until the device reported the UTC offset of its clock.
+	Location string `yaml:"location"`
+	// Interval overrides the polling interval for this target in polling mode.
+	Interval time.Duration `yaml:"interval"`
+	// PerformanceWindow is how far back performance samples are requested and
+	// PerformanceInterval is the sample interval of the device.
+	PerformanceWindow   time.Duration `yaml:"performanceWindow"`
+	PerformanceInterval time.Duration `yaml:"performanceInterval"`
+	// Timeout, MaxIdleConns and IdleConnTimeout tune the HTTP connections to the target.
+	Timeout         time.Duration `yaml:"timeout"`
+	MaxIdleConns    int           `yaml:"maxIdleConns"`
+	IdleConnTimeout time.Duration `yaml:"idleConnTimeout"`
+	TLS             TLSConfig     `yaml:"tls"`
 }
 
-func GetConfig(filename string) (*Config, error) {
+// GetConfig loads the configuration file. Targets without a location get the
+// defaultLocation.
+func GetConfig(filename string, defaultLocation string) (*Config, error) {
 	content, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -28,5 +46,19 @@ func GetConfig(filename string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if t.Location == "" {
+			t.Location = defaultLocation
+		}
+		if t.Location != "" {
+			if _, err := time.LoadLocation(t.Location); err != nil {
+				return nil, fmt.Errorf("invalid location of target %s: %v", t.IpAddress, err)
+			}
+		}
+		if _, err := t.TLS.build(); err != nil {
+			return nil, fmt.Errorf("invalid tls configuration of target %s: %v", t.IpAddress, err)
+		}
+	}
 	return cfg, nil
 }
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certificateExpiry caches the expiry time of the certificate last presented
+// by each target.
+var certificateExpiry sync.Map
+
+// TLSConfig configures how the connection to the HMC of a target is secured.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CAs used to verify the HMC certificate instead of the system roots.
+	CAFile string `yaml:"caFile"`
+	// ServerName overrides the host name the HMC certificate is verified against.
+	ServerName string `yaml:"serverName"`
+	// CertFile and KeyFile are an optional client certificate and key.
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	// PinnedSHA256 are SHA-256 fingerprints of accepted HMC certificates. Without a
+	// CAFile a pinned certificate is accepted without verifying its chain.
+	PinnedSHA256 []string `yaml:"pinnedSHA256"`
+	// InsecureSkipVerify disables all verification of the HMC certificate.
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+}
+
+// build returns the tls.Config described by c.
+func (c TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %s: %v", c.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s: %v", c.CertFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if len(c.PinnedSHA256) > 0 && !c.InsecureSkipVerify {
+		pins := make(map[string]bool)
+		for _, pin := range c.PinnedSHA256 {
+			fingerprint := strings.ToLower(strings.Replace(pin, ":", "", -1))
+			if len(fingerprint) != sha256.Size*2 {
+				return nil, fmt.Errorf("invalid SHA-256 fingerprint %q", pin)
+			}
+			pins[fingerprint] = true
+		}
+		if c.CAFile == "" {
+			// The pin replaces the chain verification, the certificate is checked below.
+			tlsConfig.InsecureSkipVerify = true
+		}
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("no certificate presented by the HMC")
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if !pins[hex.EncodeToString(sum[:])] {
+				return fmt.Errorf("certificate of the HMC with SHA-256 fingerprint %x is not pinned", sum)
+			}
+			return nil
+		}
+	}
+	return tlsConfig, nil
+}
+
+// CertificateExpiry returns the expiry time of the certificate last presented
+// by the HMC of the target.
+func CertificateExpiry(ipAddress string) (time.Time, bool) {
+	expiry, ok := certificateExpiry.Load(ipAddress)
+	if !ok {
+		return time.Time{}, false
+	}
+	return expiry.(time.Time), true
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+const (
+	// tokenTimeLayout is the layout of the expiry time in the token response.
+	tokenTimeLayout = "2006-01-02T15:04:05-0700"
+	// tokenRefreshMargin is how long before its expiry a token is refreshed.
+	tokenRefreshMargin = time.Minute
+	minAuthBackoff     = 5 * time.Second
+	maxAuthBackoff     = 5 * time.Minute
+	// logoutTimeout limits how long logging out the tokens may delay the shutdown.
+	logoutTimeout = 5 * time.Second
+)
+
+// tokenManagers caches one tokenManager per target.
+var tokenManagers sync.Map
+
+// tokenManager keeps the auth token of one target valid. It refreshes the
+// token before it expires or idles out and backs off exponentially when
+// authentication fails. The mutex is never held during requests to the HMC,
+// concurrent callers wait on refreshing for a login in progress instead.
+type tokenManager struct {
+	mu          sync.Mutex
+	refreshing  chan struct{}
+	token       string
+	issued      time.Time
+	expires     time.Time
+	idleTimeout time.Duration
+	lastUsed    time.Time
+	failures    int
+	nextAttempt time.Time
+	// logout is set to the client that obtained the token, to log it out on shutdown.
+	logout *DS8kClient
+}
+
+func tokenManagerFor(ipAddress string) *tokenManager {
+	m, _ := tokenManagers.LoadOrStore(ipAddress, &tokenManager{})
+	return m.(*tokenManager)
+}
+
+// get returns a valid token, authenticating with the client if needed. cached
+// reports whether the token was reused.
+func (m *tokenManager) get(ctx context.Context, c *DS8kClient) (token string, cached bool, err error) {
+	m.mu.Lock()
+	for m.refreshing != nil {
+		// Another scrape is logging in, wait for its token.
+		refreshing := m.refreshing
+		m.mu.Unlock()
+		select {
+		case <-refreshing:
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		}
+		m.mu.Lock()
+	}
+	if err := ctx.Err(); err != nil {
+		m.mu.Unlock()
+		return "", false, err
+	}
+	now := time.Now()
+	if m.valid(now) {
+		m.mu.Unlock()
+		return m.token, true, nil
+	}
+	if now.Before(m.nextAttempt) {
+		m.mu.Unlock()
+		return "", false, fmt.Errorf("authentication to %s failed %d times, retrying after %s", c.IpAddress, m.failures, m.nextAttempt.Format(time.RFC3339))
+	}
+	m.token = ""
+	refreshing := make(chan struct{})
+	m.refreshing = refreshing
+	m.mu.Unlock()
+
+	token, expires, idleTimeout, err := c.requestToken(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshing = nil
+	close(refreshing)
+	if err != nil {
+		if ctx.Err() != nil {
+			// The scrape gave up, that is no reason to back off.
+			return "", false, err
+		}
+		m.failures++
+		backoff := minAuthBackoff << uint(m.failures-1)
+		if backoff > maxAuthBackoff || backoff <= 0 {
+			backoff = maxAuthBackoff
+		}
+		m.nextAttempt = now.Add(backoff)
+		return "", false, err
+	}
+	log.Debugf("Obtained auth token for %s expiring at %s", c.IpAddress, expires)
+	m.token = token
+	m.issued = now
+	m.expires = expires
+	m.idleTimeout = idleTimeout
+	m.lastUsed = now
+	m.failures = 0
+	m.nextAttempt = time.Time{}
+	m.logout = c
+	return token, false, nil
+}
+
+// valid reports whether the token can still be used without being refreshed.
+func (m *tokenManager) valid(now time.Time) bool {
+	if m.token == "" {
+		return false
+	}
+	if !m.expires.IsZero() && now.After(m.expires.Add(-tokenRefreshMargin)) {
+		return false
+	}
+	if m.idleTimeout > 0 && now.After(m.lastUsed.Add(m.idleTimeout-tokenRefreshMargin)) {
+		return false
+	}
+	return true
+}
+
+// touch records that the token was used successfully.
+func (m *tokenManager) touch(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token == token {
+		m.lastUsed = time.Now()
+	}
+}
+
+// invalidate drops the token if it is still the current one.
+func (m *tokenManager) invalidate(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token == token {
+		log.Infof("Invalidating authToken for %s issued at %s", m.logout.IpAddress, m.issued.Format(time.RFC3339))
+		m.token = ""
+	}
+}
+
+// Logout logs out the auth tokens of all targets in parallel, giving up
+// after logoutTimeout so unreachable HMCs don't delay the shutdown.
+func Logout() {
+	ctx, cancel := context.WithTimeout(context.Background(), logoutTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	tokenManagers.Range(func(key, value interface{}) bool {
+		wg.Add(1)
+		go func(m *tokenManager) {
+			defer wg.Done()
+			m.logoutToken(ctx)
+		}(value.(*tokenManager))
+		return true
+	})
+	wg.Wait()
+}
+
+// logoutToken logs out the current token, if there is one. A login in
+// progress is waited for until ctx is done.
+func (m *tokenManager) logoutToken(ctx context.Context) {
+	m.mu.Lock()
+	for m.refreshing != nil {
+		refreshing := m.refreshing
+		m.mu.Unlock()
+		select {
+		case <-refreshing:
+		case <-ctx.Done():
+			return
+		}
+		m.mu.Lock()
+	}
+	token, c := m.token, m.logout
+	m.mu.Unlock()
+	if token == "" || c == nil {
+		return
+	}
+	reqLogoutURL := "https://" + c.IpAddress + ":8452/api/v1/tokens"
+	req, _ := http.NewRequest("DELETE", reqLogoutURL, nil)
+	req = req.WithContext(ctx)
+	req.Header.Add("X-Auth-Token", token)
+	resp, err := c.do(req)
+	if err != nil {
+		log.Errorf("Logging out auth token for %s failed: %v", c.IpAddress, err)
+		return
+	}
+	resp.Body.Close()
+	log.Debugf("Logged out auth token for %s", c.IpAddress)
+	m.mu.Lock()
+	if m.token == token {
+		m.token = ""
+	}
+	m.mu.Unlock()
+}